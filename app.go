@@ -2,21 +2,30 @@ package xrpc
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/samber/do"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v3"
 )
 
 type IApp interface {
 	Injector() *do.Injector
 	Spec(modifier func(TRPCSpec) TRPCSpec)
-	GenerateSpec()
+	GenerateSpec() error
+	Logger() *slog.Logger
+	Metrics() *Metrics
+	Tracer() trace.Tracer
 	Server() *echo.Echo
 	Ctx(...func(Context[any, any]) Context[any, any]) Context[any, any]
 	Use(...ProcedureCallback[any, any]) IApp
+	Auth(...Authenticator) IApp
 	Router(string, ...func(string, IApp)) IApp
 	Get(Route) string
 	Post(Route) string
@@ -24,9 +33,10 @@ type IApp interface {
 }
 
 type Route struct {
-	path        string
-	handler     func(c echo.Context) error
-	middlewares []echo.MiddlewareFunc
+	path          string
+	handler       func(c echo.Context) error
+	middlewares   []echo.MiddlewareFunc
+	procedureType XRPCSpecProcedureType
 }
 
 type App struct {
@@ -36,6 +46,10 @@ type App struct {
 	injector    *do.Injector
 	srv         *echo.Echo
 	ctx         Context[any, any]
+	logger      *slog.Logger
+	metrics     *Metrics
+	tracer      trace.Tracer
+	propagator  propagation.TextMapPropagator
 }
 
 func (a *App) Injector() *do.Injector {
@@ -58,19 +72,31 @@ func (a *App) Spec(modifier func(TRPCSpec) TRPCSpec) {
 	a.spec = modifier(a.spec)
 }
 
-func (a *App) GenerateSpec() {
-	a.Spec(func(t TRPCSpec) TRPCSpec {
-		yamlData, err := yaml.Marshal(&t)
-		if err != nil {
-			log.Fatalf("Error marshaling YAML: %v", err)
-		}
-		err = WriteFile(a.specPath, string(yamlData))
-		if err != nil {
-			log.Fatalln(err)
-		}
+func (a *App) GenerateSpec() error {
+	if err := validateSpecEnums(a.spec); err != nil {
+		return fmt.Errorf("invalid spec:\n%w", err)
+	}
 
-		return t
-	})
+	yamlData, err := yaml.Marshal(&a.spec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal spec: %w", err)
+	}
+
+	return WriteFile(a.specPath, string(yamlData))
+}
+
+func (a *App) Logger() *slog.Logger {
+	return a.logger
+}
+
+// Metrics returns the procedure metrics, or nil unless XRPCConfig.Metrics is
+// set.
+func (a *App) Metrics() *Metrics {
+	return a.metrics
+}
+
+func (a *App) Tracer() trace.Tracer {
+	return a.tracer
 }
 
 func (a *App) Router(path string, procedures ...func(string, IApp)) IApp {
@@ -80,6 +106,7 @@ func (a *App) Router(path string, procedures ...func(string, IApp)) IApp {
 
 	a.Ctx(func(c Context[any, any]) Context[any, any] {
 		c.rootMiddlewares = []ProcedureCallback[any, any]{}
+		c.rootAuth = []XRPCSpecAuth{}
 		return c
 	})
 
@@ -95,19 +122,74 @@ func (a *App) Use(middlewares ...ProcedureCallback[any, any]) IApp {
 	return a
 }
 
+// Auth requires callers of the procedures registered next, up to the end of
+// the Router call, to authenticate with any of the authenticators. Each call
+// adds a requirement, which is also published in the spec.
+func (a *App) Auth(authenticators ...Authenticator) IApp {
+	a.Use(Authenticate[any, any](authenticators...))
+	a.Ctx(func(c Context[any, any]) Context[any, any] {
+		c.rootAuth = append(c.rootAuth, specAuth(authenticators))
+		return c
+	})
+
+	return a
+}
+
 func (a *App) Get(route Route) string {
-	return a.srv.GET(route.path, route.handler, route.middlewares...).Path
+	return a.srv.GET(route.path, route.handler, a.routeMiddlewares(route)...).Path
 }
 
 func (a *App) Post(route Route) string {
-	return a.srv.POST(route.path, route.handler, route.middlewares...).Path
+	return a.srv.POST(route.path, route.handler, a.routeMiddlewares(route)...).Path
+}
+
+// routeMiddlewares runs procedure routes through tracing and metrics, and
+// tags them for the access log, before their own middlewares.
+func (a *App) routeMiddlewares(route Route) []echo.MiddlewareFunc {
+	if route.procedureType == "" {
+		return route.middlewares
+	}
+
+	middlewares := []echo.MiddlewareFunc{procedureType(route.procedureType), requestDone}
+	if a.metrics != nil {
+		middlewares = append([]echo.MiddlewareFunc{a.metrics.middleware(route.path, route.procedureType)}, middlewares...)
+	}
+	middlewares = append([]echo.MiddlewareFunc{tracing(a.tracer, a.propagator, route.path, route.procedureType)}, middlewares...)
+
+	return append(middlewares, route.middlewares...)
+}
+
+const doneKey = "xrpc.done"
+
+// onDone runs fn once the procedure's request is handled, e.g. to release a
+// resource a middleware acquired for it.
+func onDone(c echo.Context, fn func()) {
+	fns, _ := c.Get(doneKey).([]func())
+	c.Set(doneKey, append(fns, fn))
+}
+
+func requestDone(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		defer func() {
+			fns, _ := c.Get(doneKey).([]func())
+			for i := len(fns) - 1; i >= 0; i-- {
+				fns[i]()
+			}
+		}()
+
+		return next(c)
+	}
 }
 
 func (a *App) Start(port int) error {
 	if a.autoGenSpec {
-		a.GenerateSpec()
+		if err := a.GenerateSpec(); err != nil {
+			return err
+		}
 	}
 
+	a.logger.Info("server started", "port", port)
+
 	return a.srv.Start(fmt.Sprintf(":%d", port))
 }
 
@@ -116,6 +198,46 @@ type XRPCConfig struct {
 	ServerUrl       string
 	AutoGenTRPCSpec bool
 	SpecPath        string
+	// Explorer serves the live spec at /xrpc/spec.json and /xrpc/spec.yaml,
+	// and an API explorer for calling procedures at /xrpc/.
+	Explorer bool
+	// Clients are generated on request at /xrpc/<name>, e.g.
+	// clients.Downloads() serves /xrpc/client.ts.
+	Clients map[string]ClientGenerator
+	// Logger receives registration and access logs, defaults to slog.Default().
+	Logger *slog.Logger
+	// Metrics records per-procedure metrics, served in the Prometheus text
+	// format at /metrics.
+	Metrics bool
+	// TracerProvider creates a span per procedure call, with child spans for
+	// validation, middlewares and the handler. Defaults to the global
+	// provider, otel.GetTracerProvider().
+	TracerProvider trace.TracerProvider
+	// Propagator reads the caller's trace context from request headers,
+	// defaults to W3C trace context and baggage.
+	Propagator propagation.TextMapPropagator
+	// Policies authorizes procedures by path, in addition to their own
+	// IProcedure.Authorize policies.
+	Policies PolicyTable
+	// RequirePolicies denies every call to procedures without an auth
+	// requirement or policy, so nothing is public unless marked xrpc.Public().
+	RequirePolicies bool
+	// CacheStore holds the responses of procedures with a Cache policy,
+	// defaults to a MemoryCacheStore of 1024 responses.
+	CacheStore CacheStore
+	// CORS lets browsers call procedures from other origins, unless nil.
+	CORS *CORSConfig
+	// SecurityHeaders adds X-Content-Type-Options, X-Frame-Options,
+	// Referrer-Policy and optionally HSTS and CSP headers, unless nil.
+	SecurityHeaders *SecurityHeadersConfig
+	// MaxBodySize rejects requests with larger bodies, in bytes, with a 413,
+	// unless zero.
+	MaxBodySize int64
+	// Gzip compresses responses of 1 KiB or more for clients accepting it.
+	Gzip bool
+	// Timeout cancels the context of requests taking longer, failing those
+	// whose handler returns the context's error with a 503, unless zero.
+	Timeout time.Duration
 }
 
 func NewXRPC(cfg ...XRPCConfig) IApp {
@@ -130,29 +252,91 @@ func NewXRPC(cfg ...XRPCConfig) IApp {
 		_cfg = cfg[0]
 	}
 
+	if _cfg.Logger == nil {
+		_cfg.Logger = slog.Default()
+	}
+
+	if _cfg.TracerProvider == nil {
+		_cfg.TracerProvider = otel.GetTracerProvider()
+	}
+
+	if _cfg.Propagator == nil {
+		_cfg.Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+
+	if _cfg.CacheStore == nil {
+		_cfg.CacheStore = NewMemoryCacheStore(1024)
+	}
+
+	if _cfg.CORS != nil {
+		cors := *_cfg.CORS
+		// Browsers reject credentials with a wildcard origin, and reflecting
+		// any origin instead would let every site act as the user.
+		if cors.AllowCredentials && (len(cors.AllowOrigins) == 0 || lo.Contains(cors.AllowOrigins, "*")) {
+			_cfg.Logger.Error("CORS AllowCredentials needs explicit AllowOrigins, not \"*\", disabling credentials")
+			cors.AllowCredentials = false
+		}
+		if len(cors.AllowOrigins) == 0 {
+			cors.AllowOrigins = []string{"*"}
+		}
+		if cors.MaxAge <= 0 {
+			cors.MaxAge = 10 * time.Minute
+		}
+		_cfg.CORS = &cors
+	}
+
+	tracer := _cfg.TracerProvider.Tracer(tracerName)
+
 	srv := echo.New()
+	srv.HideBanner = true
+	srv.HidePort = true
 
 	srv.Pre(middleware.AddTrailingSlash())
-	srv.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Format: "${method} ${uri} - ${status} ${latency_human}\n",
-	}))
+	srv.Use(middleware.RequestID())
+	srv.Use(accessLog(_cfg.Logger))
+	srv.Use(serverMiddlewares(_cfg)...)
 
 	i := do.New()
 
-	return &App{
+	app := &App{
 		spec: TRPCSpec{
-			Name:      _cfg.Name,
-			ServerUrl: _cfg.ServerUrl,
+			SpecVersion: SpecVersion,
+			Name:        _cfg.Name,
+			ServerUrl:   _cfg.ServerUrl,
+			Server:      specServer(_cfg),
 		},
 		autoGenSpec: _cfg.AutoGenTRPCSpec,
 		specPath:    _cfg.SpecPath,
 		injector:    i,
 		srv:         srv,
+		logger:      _cfg.Logger,
+		tracer:      tracer,
+		propagator:  _cfg.Propagator,
 		ctx: Context[any, any]{
 			sharedValue:     map[string]any{},
+			logger:          _cfg.Logger,
+			tracer:          tracer,
 			Injector:        i,
 			rootMiddlewares: []ProcedureCallback[any, any]{},
+			rootAuth:        []XRPCSpecAuth{},
+			policyTable:     _cfg.Policies,
+			requirePolicies: _cfg.RequirePolicies,
+			cacheStore:      _cfg.CacheStore,
 			middlewares:     []ProcedureCallback[any, any]{},
 		},
 	}
+
+	srv.HTTPErrorHandler = app.errorHandler
+
+	if _cfg.Metrics {
+		app.metrics = NewMetrics()
+		app.serveMetrics()
+	}
+
+	if _cfg.Explorer {
+		app.serveSpec(_cfg.Clients)
+	}
+	app.serveClients(_cfg.Clients)
+
+	return app
 }
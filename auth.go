@@ -0,0 +1,109 @@
+package xrpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+	"github.com/samber/lo"
+)
+
+const principalKey = "xrpc.principal"
+
+// Principal is the authenticated caller of a procedure.
+type Principal struct {
+	Subject string `json:"subject"`
+	// Scheme is the auth type that authenticated the caller.
+	Scheme XRPCSpecAuthType `json:"scheme"`
+	Roles  []string         `json:"roles,omitempty"`
+	Scopes []string         `json:"scopes,omitempty"`
+	// Claims holds the verified JWT claims, or the metadata of an API key.
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && lo.Contains(p.Roles, role)
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && lo.Contains(p.Scopes, scope)
+}
+
+// Authenticator verifies the credentials of a request for one auth scheme.
+type Authenticator interface {
+	// Authenticate returns a nil principal and error when the request has no
+	// credentials for the scheme, so the next authenticator can be tried.
+	// An *XRPCError keeps its code, any other error fails with 401.
+	Authenticate(r *http.Request, injector *do.Injector) (*Principal, error)
+	SpecScheme() XRPCSpecAuthScheme
+}
+
+// Authenticate is a middleware accepting a request authenticated by any of
+// the authenticators, which places the principal on the Context. Prefer
+// IApp.Auth and IProcedure.Auth, which also publish the requirement in the
+// spec.
+func Authenticate[T, R any](authenticators ...Authenticator) ProcedureCallback[T, R] {
+	return func(c Context[T, R]) error {
+		for _, authenticator := range authenticators {
+			principal, err := authenticator.Authenticate(c.ec.Request(), c.Injector)
+			if err != nil {
+				c.ec.Response().Header().Set("WWW-Authenticate", wwwAuthenticate(authenticators))
+
+				var xrpcErr *XRPCError
+				if errors.As(err, &xrpcErr) {
+					return xrpcErr
+				}
+				return &XRPCError{Code: http.StatusUnauthorized, Detail: err.Error()}
+			}
+
+			if principal != nil {
+				authenticated := *principal
+				authenticated.Scheme = authenticator.SpecScheme().Type
+				c.ec.Set(principalKey, &authenticated)
+				return nil
+			}
+		}
+
+		c.ec.Response().Header().Set("WWW-Authenticate", wwwAuthenticate(authenticators))
+
+		return &XRPCError{Code: http.StatusUnauthorized, Detail: "authentication required"}
+	}
+}
+
+// callerScope identifies the caller of a request for keys of cached and
+// replayed responses: the principal when an authenticator set one, or else a
+// hash of the Authorization and Cookie headers, as middlewares may have
+// authenticated the request from those. It is empty for anonymous requests.
+func callerScope(c echo.Context) string {
+	if principal, _ := c.Get(principalKey).(*Principal); principal != nil {
+		return string(principal.Scheme) + ":" + principal.Subject
+	}
+
+	header := c.Request().Header
+	if header.Get(echo.HeaderAuthorization) == "" && header.Get(echo.HeaderCookie) == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(header.Get(echo.HeaderAuthorization) + "\n" + header.Get(echo.HeaderCookie)))
+	return "credentials:" + hex.EncodeToString(sum[:])
+}
+
+func wwwAuthenticate(authenticators []Authenticator) string {
+	for _, authenticator := range authenticators {
+		if authenticator.SpecScheme().Type == XRPCSpecAuthTypeBearer {
+			return "Bearer"
+		}
+	}
+
+	return "APIKey"
+}
+
+// specAuth describes the requirement met by any of the authenticators.
+func specAuth(authenticators []Authenticator) XRPCSpecAuth {
+	return XRPCSpecAuth{Schemes: lo.Map(authenticators, func(authenticator Authenticator, _ int) XRPCSpecAuthScheme {
+		return authenticator.SpecScheme()
+	})}
+}
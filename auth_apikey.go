@@ -0,0 +1,82 @@
+package xrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/samber/do"
+)
+
+// APIKeyStore resolves API keys to their owners.
+type APIKeyStore interface {
+	// LookupAPIKey returns the principal owning key, or nil when the key is
+	// unknown or revoked.
+	LookupAPIKey(ctx context.Context, key string) (*Principal, error)
+}
+
+// StaticAPIKeys is an APIKeyStore over a fixed set of keys, e.g. for
+// development or service-to-service calls.
+type StaticAPIKeys map[string]Principal
+
+func (s StaticAPIKeys) LookupAPIKey(_ context.Context, key string) (*Principal, error) {
+	for candidate, principal := range s {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			return &principal, nil
+		}
+	}
+
+	return nil, nil
+}
+
+type APIKeyConfig struct {
+	// Header carries the key, defaults to X-API-Key.
+	Header string
+	// Store looks up keys. When nil, the APIKeyStore provided to the app's
+	// injector is used, e.g. do.ProvideValue[xrpc.APIKeyStore](app.Injector(), store).
+	Store APIKeyStore
+}
+
+// APIKeyAuth authenticates API keys sent in a header.
+type APIKeyAuth struct {
+	cfg APIKeyConfig
+}
+
+func NewAPIKeyAuth(cfg APIKeyConfig) *APIKeyAuth {
+	if cfg.Header == "" {
+		cfg.Header = "X-API-Key"
+	}
+
+	return &APIKeyAuth{cfg: cfg}
+}
+
+func (a *APIKeyAuth) Authenticate(r *http.Request, injector *do.Injector) (*Principal, error) {
+	key := r.Header.Get(a.cfg.Header)
+	if key == "" {
+		return nil, nil
+	}
+
+	store := a.cfg.Store
+	if store == nil {
+		var err error
+		store, err = do.Invoke[APIKeyStore](injector)
+		if err != nil {
+			return nil, &XRPCError{Code: http.StatusInternalServerError, Detail: "no API key store is configured"}
+		}
+	}
+
+	principal, err := store.LookupAPIKey(r.Context(), key)
+	if err != nil {
+		return nil, &XRPCError{Code: http.StatusInternalServerError, Detail: "failed to look up API key"}
+	}
+	if principal == nil {
+		return nil, errors.New("invalid API key")
+	}
+
+	return principal, nil
+}
+
+func (a *APIKeyAuth) SpecScheme() XRPCSpecAuthScheme {
+	return XRPCSpecAuthScheme{Type: XRPCSpecAuthTypeAPIKey, Header: a.cfg.Header}
+}
@@ -0,0 +1,186 @@
+package xrpc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/samber/do"
+	"github.com/samber/lo"
+)
+
+type JWTConfig struct {
+	// Secret verifies HS256, HS384 and HS512 tokens.
+	Secret []byte
+	// PublicKeys verify RS256, RS384 and RS512 tokens, by their kid header.
+	// A token without a kid is accepted when there is a single key.
+	PublicKeys map[string]*rsa.PublicKey
+	// JWKSFile adds the RSA keys of a local JSON Web Key Set to PublicKeys.
+	JWKSFile string
+	Issuer   string
+	Audience string
+	// Leeway allows for clock skew when checking exp, nbf and iat. Tokens
+	// must have an exp and a sub claim.
+	Leeway time.Duration
+	// RolesClaim and ScopesClaim name the claims read into the principal,
+	// defaulting to "roles" and "scope". Either may be a list or a space
+	// separated string.
+	RolesClaim  string
+	ScopesClaim string
+}
+
+// JWTAuth authenticates bearer JWTs from the Authorization header.
+type JWTAuth struct {
+	cfg     JWTConfig
+	options []jwt.ParserOption
+}
+
+func NewJWTAuth(cfg JWTConfig) (*JWTAuth, error) {
+	cfg.PublicKeys = lo.Assign(cfg.PublicKeys)
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.PublicKeys = lo.Assign(cfg.PublicKeys, keys)
+	}
+
+	methods := []string{}
+	if len(cfg.Secret) > 0 {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if len(cfg.PublicKeys) > 0 {
+		methods = append(methods, "RS256", "RS384", "RS512")
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("jwt auth needs a secret, public keys or a JWKS file")
+	}
+
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = "roles"
+	}
+	if cfg.ScopesClaim == "" {
+		cfg.ScopesClaim = "scope"
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithLeeway(cfg.Leeway), jwt.WithIssuedAt(), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &JWTAuth{cfg: cfg, options: options}, nil
+}
+
+func (a *JWTAuth) Authenticate(r *http.Request, _ *do.Injector) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return nil, nil
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(header[len("Bearer "):], claims, a.key, a.options...); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	// Caches and idempotency keys are scoped to the subject, so every token
+	// must name one.
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errors.New("invalid token: missing sub claim")
+	}
+
+	return &Principal{
+		Subject: subject,
+		Roles:   claimStrings(claims[a.cfg.RolesClaim]),
+		Scopes:  claimStrings(claims[a.cfg.ScopesClaim]),
+		Claims:  claims,
+	}, nil
+}
+
+func (a *JWTAuth) SpecScheme() XRPCSpecAuthScheme {
+	return XRPCSpecAuthScheme{Type: XRPCSpecAuthTypeBearer, Format: "JWT"}
+}
+
+func (a *JWTAuth) key(token *jwt.Token) (any, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return a.cfg.Secret, nil
+	case *jwt.SigningMethodRSA:
+		kid, _ := token.Header["kid"].(string)
+		if key, exists := a.cfg.PublicKeys[kid]; exists {
+			return key, nil
+		}
+		if kid == "" && len(a.cfg.PublicKeys) == 1 {
+			return lo.Values(a.cfg.PublicKeys)[0], nil
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
+
+func claimStrings(claim any) []string {
+	switch claim := claim.(type) {
+	case string:
+		return strings.Fields(claim)
+	case []any:
+		return lo.FilterMap(claim, func(value any, _ int) (string, bool) {
+			s, ok := value.(string)
+			return s, ok
+		})
+	}
+
+	return nil
+}
+
+// loadJWKS reads the RSA public keys of a JSON Web Key Set, by key id.
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+
+	set := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS %s: %w", path, err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for key %q in %s: %w", key.Kid, path, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for key %q in %s: %w", key.Kid, path, err)
+		}
+
+		keys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	return keys, nil
+}
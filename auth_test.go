@@ -0,0 +1,76 @@
+package xrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type authWhoAmI struct {
+	Subject   string `json:"subject"`
+	RequestID string `json:"request_id"`
+	Caller    string `json:"caller"`
+}
+
+func TestAuthConcurrentRequests(t *testing.T) {
+	keys := StaticAPIKeys{}
+	for i := range 8 {
+		keys[fmt.Sprintf("key-%d", i)] = Principal{Subject: fmt.Sprintf("user-%d", i)}
+	}
+
+	app := NewXRPC(XRPCConfig{Name: "Auth", AutoGenTRPCSpec: false})
+	app.Use(func(c Context[any, any]) error {
+		c.Locals("caller", c.Header("X-API-Key"))
+		return nil
+	})
+	app.Auth(NewAPIKeyAuth(APIKeyConfig{Store: keys}))
+	app.Router("auth", NewProcedure[struct{}, authWhoAmI]("whoami").Query(func(c Context[struct{}, authWhoAmI]) error {
+		return c.Json(http.StatusOK, authWhoAmI{Subject: c.Principal().Subject, RequestID: c.RequestID(), Caller: c.Locals("caller").(string)})
+	}))
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range 50 {
+				key, requestID := fmt.Sprintf("key-%d", i), fmt.Sprintf("req-%d-%d", i, j)
+
+				req := httptest.NewRequest(http.MethodGet, "/auth/whoami/", nil)
+				req.Header.Set("X-API-Key", key)
+				req.Header.Set("X-Request-ID", requestID)
+				rec := httptest.NewRecorder()
+				app.Server().ServeHTTP(rec, req)
+
+				var got authWhoAmI
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); rec.Code != http.StatusOK || err != nil {
+					t.Errorf("status %d: %s", rec.Code, rec.Body)
+					return
+				}
+				if want := (authWhoAmI{Subject: fmt.Sprintf("user-%d", i), RequestID: requestID, Caller: key}); got != want {
+					t.Errorf("got %+v, want %+v", got, want)
+					return
+				}
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 50 {
+				rec := httptest.NewRecorder()
+				app.Server().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/whoami/", nil))
+				if rec.Code != http.StatusUnauthorized {
+					t.Errorf("unauthenticated request: status %d: %s", rec.Code, rec.Body)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,228 @@
+package xrpc
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+)
+
+// cacheKeySeparator ends the procedure path at the start of cache keys.
+const cacheKeySeparator = "\x00"
+
+// CachedResponse is a query response stored for reuse.
+type CachedResponse struct {
+	Status      int
+	ContentType string
+	ETag        string
+	Body        []byte
+}
+
+// CacheStore holds cached query responses until their ttl passes. Keys start
+// with the procedure's path, so one Invalidate call drops a whole procedure.
+type CacheStore interface {
+	// Get returns the response stored at key, or nil when it is missing or
+	// expired.
+	Get(ctx context.Context, key string) (*CachedResponse, error)
+	Set(ctx context.Context, key string, response CachedResponse, ttl time.Duration) error
+	// Invalidate drops the responses whose key starts with prefix.
+	Invalidate(ctx context.Context, prefix string) error
+}
+
+// MemoryCacheStore keeps up to a number of responses in memory, evicting the
+// least recently used ones.
+type MemoryCacheStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key      string
+	response CachedResponse
+	expires  time.Time
+}
+
+func NewMemoryCacheStore(capacity int) *MemoryCacheStore {
+	return &MemoryCacheStore{capacity: capacity, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (s *MemoryCacheStore) Get(_ context.Context, key string) (*CachedResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, exists := s.entries[key]
+	if !exists {
+		return nil, nil
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		s.remove(element)
+		return nil, nil
+	}
+	s.order.MoveToFront(element)
+
+	return &entry.response, nil
+}
+
+func (s *MemoryCacheStore) Set(_ context.Context, key string, response CachedResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := &cacheEntry{key: key, response: response, expires: time.Now().Add(ttl)}
+	if element, exists := s.entries[key]; exists {
+		element.Value = entry
+		s.order.MoveToFront(element)
+		return nil
+	}
+
+	s.entries[key] = s.order.PushFront(entry)
+	for s.capacity > 0 && s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+
+	return nil
+}
+
+func (s *MemoryCacheStore) Invalidate(_ context.Context, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, element := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			s.remove(element)
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryCacheStore) remove(element *list.Element) {
+	delete(s.entries, element.Value.(*cacheEntry).key)
+	s.order.Remove(element)
+}
+
+type cachePolicy struct {
+	ttl    time.Duration
+	varyBy []string
+	public bool
+}
+
+func (p *cachePolicy) spec() *XRPCSpecCache {
+	if p == nil {
+		return nil
+	}
+
+	return &XRPCSpecCache{MaxAge: ceilSeconds(p.ttl), VaryBy: p.varyBy}
+}
+
+// serve replies with the cached response of the query when there is one, or
+// runs next and caches its 200 response. Responses carry an ETag and are
+// reduced to a 304 when the client already has them. Keys include the query
+// string, the caller and the varyBy headers.
+func (p *cachePolicy) serve(c echo.Context, store CacheStore, next func() error) error {
+	caller := callerScope(c)
+	key := c.Path() + cacheKeySeparator + c.Request().URL.Query().Encode() + cacheKeySeparator + caller
+	for _, header := range p.varyBy {
+		key += cacheKeySeparator + c.Request().Header.Get(header)
+	}
+
+	// Shared caches must not keep responses to requests with credentials,
+	// whether or not an authenticator read them.
+	public := p.public && caller == ""
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderCacheControl, fmt.Sprintf("%s, max-age=%d", lo.Ternary(public, "public", "private"), ceilSeconds(p.ttl)))
+	if len(p.varyBy) > 0 {
+		header.Set(echo.HeaderVary, strings.Join(p.varyBy, ", "))
+	}
+
+	ctx := c.Request().Context()
+	if cached, err := store.Get(ctx, key); err == nil && cached != nil {
+		header.Set("X-Cache", "HIT")
+		return writeCached(c, *cached)
+	}
+	header.Set("X-Cache", "MISS")
+
+	response := c.Response()
+	writer := &bufferedWriter{ResponseWriter: response.Writer}
+	response.Writer = writer
+	err := next()
+	response.Writer = writer.ResponseWriter
+
+	if !response.Committed {
+		header.Del(echo.HeaderCacheControl)
+		return err
+	}
+	response.Committed, response.Size = false, 0
+
+	cached := CachedResponse{Status: writer.status, ContentType: header.Get(echo.HeaderContentType), Body: writer.body.Bytes()}
+	if cached.Status != http.StatusOK {
+		header.Del(echo.HeaderCacheControl)
+		return c.Blob(cached.Status, cached.ContentType, cached.Body)
+	}
+
+	sum := sha256.Sum256(cached.Body)
+	cached.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	store.Set(ctx, key, cached, p.ttl)
+
+	return writeCached(c, cached)
+}
+
+func writeCached(c echo.Context, cached CachedResponse) error {
+	c.Response().Header().Set("ETag", cached.ETag)
+	if etagMatches(c.Request().Header.Get("If-None-Match"), cached.ETag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.Blob(cached.Status, cached.ContentType, cached.Body)
+}
+
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bufferedWriter holds back a response, so headers can still be set once it
+// is written.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	return w.body.Write(p)
+}
+
+// cacheInvalidationPrefix matches the cache keys of the procedure at path, or
+// of every procedure under the prefix when path ends in *.
+func cacheInvalidationPrefix(path string) string {
+	prefix, wildcard := strings.CutSuffix(path, "*")
+	if wildcard {
+		return JoinPath(prefix)
+	}
+
+	return JoinPath(path) + cacheKeySeparator
+}
@@ -0,0 +1,112 @@
+package xrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCacheTestApp() (IApp, *int) {
+	calls := 0
+
+	app := NewXRPC(XRPCConfig{Name: "Cache", AutoGenTRPCSpec: false})
+	app.Use(func(c Context[any, any]) error {
+		c.Locals("user", c.Header("Authorization"))
+		return nil
+	})
+	app.Router("post",
+		NewProcedure[struct{}, string]("get").Cache(time.Minute).Query(func(c Context[struct{}, string]) error {
+			calls++
+			return c.Json(http.StatusOK, c.Locals("user").(string)+":"+strconv.Itoa(calls))
+		}),
+		NewProcedure[struct{}, string]("update").Mutation(func(c Context[struct{}, string]) error {
+			if err := c.Invalidate("/post/get"); err != nil {
+				return err
+			}
+			return c.Json(http.StatusOK, "ok")
+		}),
+	)
+
+	return app, &calls
+}
+
+func cacheTestRequest(app IApp, method string, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	app.Server().ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestCache(t *testing.T) {
+	alice := http.Header{"Authorization": {"Bearer alice"}}
+	bob := http.Header{"Authorization": {"Bearer bob"}}
+
+	app, calls := newCacheTestApp()
+
+	for _, step := range []struct {
+		name   string
+		method string
+		target string
+		header http.Header
+		status int
+		cache  string
+		body   string
+	}{
+		{name: "miss", method: http.MethodGet, target: "/post/get/?id=1", header: alice, status: http.StatusOK, cache: "MISS", body: `"Bearer alice:1"`},
+		{name: "hit", method: http.MethodGet, target: "/post/get/?id=1", header: alice, status: http.StatusOK, cache: "HIT", body: `"Bearer alice:1"`},
+		{name: "other input", method: http.MethodGet, target: "/post/get/?id=2", header: alice, status: http.StatusOK, cache: "MISS", body: `"Bearer alice:2"`},
+		{name: "other caller", method: http.MethodGet, target: "/post/get/?id=1", header: bob, status: http.StatusOK, cache: "MISS", body: `"Bearer bob:3"`},
+		{name: "anonymous caller", method: http.MethodGet, target: "/post/get/?id=1", status: http.StatusOK, cache: "MISS", body: `":4"`},
+		{name: "invalidate", method: http.MethodPost, target: "/post/update/", status: http.StatusOK, body: `"ok"`},
+		{name: "miss after invalidation", method: http.MethodGet, target: "/post/get/?id=1", header: alice, status: http.StatusOK, cache: "MISS", body: `"Bearer alice:5"`},
+	} {
+		rec := cacheTestRequest(app, step.method, step.target, step.header)
+
+		if rec.Code != step.status || rec.Header().Get("X-Cache") != step.cache {
+			t.Errorf("%s: status %d, X-Cache %q, want %d, %q", step.name, rec.Code, rec.Header().Get("X-Cache"), step.status, step.cache)
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != step.body {
+			t.Errorf("%s: body %s, want %s", step.name, body, step.body)
+		}
+	}
+
+	if *calls != 5 {
+		t.Errorf("handler ran %d times, want 5", *calls)
+	}
+}
+
+func TestCacheNotModified(t *testing.T) {
+	app, calls := newCacheTestApp()
+
+	rec := cacheTestRequest(app, http.MethodGet, "/post/get/?id=1", nil)
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "private, max-age=60" {
+		t.Errorf("Cache-Control %q", got)
+	}
+
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		rec := cacheTestRequest(app, http.MethodGet, "/post/get/?id=1", http.Header{"If-None-Match": {ifNoneMatch}})
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: status %d, body %q", ifNoneMatch, rec.Code, rec.Body)
+		}
+	}
+
+	rec = cacheTestRequest(app, http.MethodGet, "/post/get/?id=1", http.Header{"If-None-Match": {`"other"`}})
+	if rec.Code != http.StatusOK {
+		t.Errorf("stale If-None-Match: status %d", rec.Code)
+	}
+
+	if *calls != 1 {
+		t.Errorf("handler ran %d times, want 1", *calls)
+	}
+}
@@ -0,0 +1,317 @@
+package clients
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc"
+)
+
+type DartClientConfig struct {
+	Spec     xrpc.TRPCSpec
+	Output   string
+	PostHook func()
+}
+
+var dartSyntax = typeSyntax{
+	primitives: map[string]string{
+		"string":          "String",
+		"int":             "int",
+		"int8":            "int",
+		"int16":           "int",
+		"int32":           "int",
+		"int64":           "int",
+		"uint":            "int",
+		"uint8":           "int",
+		"uint16":          "int",
+		"uint32":          "int",
+		"uint64":          "int",
+		"float32":         "double",
+		"float64":         "double",
+		"bool":            "bool",
+		"interface{}":     "dynamic",
+		"interface {}":    "dynamic",
+		"any":             "dynamic",
+		"nil":             "void",
+		"time.Time":       "String",
+		"time.Duration":   "int",
+		"json.RawMessage": "dynamic",
+	},
+	array: func(elem string) string { return "List<" + elem + ">" },
+	dict:  func(key, value string) string { return "Map<" + key + ", " + value + ">" },
+	named: func(name string) string { return lo.PascalCase(name) },
+}
+
+var dartKeywords = []string{
+	"assert", "break", "case", "catch", "class", "const", "continue", "default", "do", "else", "enum",
+	"extends", "false", "final", "finally", "for", "if", "in", "is", "new", "null", "rethrow", "return",
+	"super", "switch", "this", "throw", "true", "try", "var", "void", "while", "with",
+}
+
+func dartString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, `$`, `\$`).Replace(s) + "'"
+}
+
+func dartFieldName(alias string) string {
+	name := lo.CamelCase(alias)
+	if lo.Contains(dartKeywords, name) {
+		return name + "_"
+	}
+
+	return name
+}
+
+// dartDecode returns the expression converting decoded JSON into t.
+func dartDecode(expr string, t *goType, nullable bool) string {
+	var out string
+	switch t.kind {
+	// Go marshals nil slices and maps as null, so those decode as empty.
+	case goTypeArray:
+		out = fmt.Sprintf("((%s as List<dynamic>?) ?? const []).map((e) => %s).toList()", expr, dartDecode("e", t.elem, false))
+	case goTypeMap:
+		out = fmt.Sprintf("((%s as Map<String, dynamic>?) ?? const {}).map((k, v) => MapEntry(k, %s))", expr, dartDecode("v", t.elem, false))
+	case goTypeNamed:
+		out = fmt.Sprintf("%s.fromJson(%s as Map<String, dynamic>)", dartSyntax.spell(t), expr)
+	default:
+		switch typ := dartSyntax.spell(t); typ {
+		case "int":
+			out = fmt.Sprintf("(%s as num).toInt()", expr)
+		case "double":
+			out = fmt.Sprintf("(%s as num).toDouble()", expr)
+		case "dynamic", "void":
+			return expr
+		default:
+			out = fmt.Sprintf("%s as %s", expr, typ)
+		}
+	}
+
+	if nullable {
+		return fmt.Sprintf("%s == null ? null : %s", expr, out)
+	}
+
+	return out
+}
+
+// dartEncode returns the expression converting t into JSON-encodable values.
+func dartEncode(expr string, t *goType, nullable bool) string {
+	access := lo.Ternary(nullable, "?.", ".")
+
+	switch t.kind {
+	case goTypeArray:
+		if inner := dartEncode("e", t.elem, false); inner != "e" {
+			return fmt.Sprintf("%s%smap((e) => %s).toList()", expr, access, inner)
+		}
+	case goTypeMap:
+		if inner := dartEncode("v", t.elem, false); inner != "v" {
+			return fmt.Sprintf("%s%smap((k, v) => MapEntry(k, %s))", expr, access, inner)
+		}
+	case goTypeNamed:
+		return expr + access + "toJson()"
+	}
+
+	return expr
+}
+
+func dartClass(sb *strings.Builder, descriptor xrpc.TypeDescriptor) {
+	name := dartSyntax.named(descriptor.TypeName)
+
+	sb.WriteString(fmt.Sprintf("class %s {\n", name))
+	for _, field := range descriptor.Fields {
+		sb.WriteString(fmt.Sprintf(
+			"  final %s%s %s;\n", dartSyntax.convert(stringFieldType(field)), lo.Ternary(field.Nillable, "?", ""), dartFieldName(field.Alias),
+		))
+	}
+
+	if len(descriptor.Fields) == 0 {
+		sb.WriteString(fmt.Sprintf("\n  const %s();\n", name))
+	} else {
+		sb.WriteString(fmt.Sprintf("\n  const %s({\n", name))
+		for _, field := range descriptor.Fields {
+			sb.WriteString(fmt.Sprintf("    %sthis.%s,\n", lo.Ternary(field.Nillable, "", "required "), dartFieldName(field.Alias)))
+		}
+		sb.WriteString("  });\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("\n  factory %s.fromJson(Map<String, dynamic> json) => %s(\n", name, name))
+	for _, field := range descriptor.Fields {
+		sb.WriteString(fmt.Sprintf(
+			"        %s: %s,\n",
+			dartFieldName(field.Alias),
+			dartDecode("json["+dartString(field.Alias)+"]", parseGoType(stringFieldType(field)), field.Nillable),
+		))
+	}
+	sb.WriteString("      );\n")
+
+	sb.WriteString("\n  Map<String, dynamic> toJson() => {\n")
+	for _, field := range descriptor.Fields {
+		value := dartEncode(dartFieldName(field.Alias), parseGoType(stringFieldType(field)), field.Nillable)
+		if field.Nillable {
+			sb.WriteString(fmt.Sprintf("        if (%s != null) %s: %s,\n", dartFieldName(field.Alias), dartString(field.Alias), value))
+		} else {
+			sb.WriteString(fmt.Sprintf("        %s: %s,\n", dartString(field.Alias), value))
+		}
+	}
+	sb.WriteString("      };\n}\n\n")
+}
+
+const dartClientRuntime = `/// Thrown by the client methods when a call gets a non-2xx response.
+class XRPCException implements Exception {
+  final int statusCode;
+  final dynamic detail;
+
+  /// Identifies the call in the server's request log.
+  final String? requestId;
+
+  const XRPCException(this.statusCode, this.detail, [this.requestId]);
+
+  /// Field-level validation messages, when the server rejected the input.
+  Map<String, dynamic>? get fieldErrors => detail is Map<String, dynamic> ? detail as Map<String, dynamic> : null;
+
+  @override
+  String toString() => 'XRPCException($statusCode): $detail';
+}
+
+final _random = Random.secure();
+
+String _newRequestId() => List.generate(16, (_) => _random.nextInt(256).toRadixString(16).padLeft(2, '0')).join();
+
+/// Whether a call failing with [status] may succeed when retried.
+bool _isRetryable(int status, bool idempotent) =>
+    const [408, 429, 500, 502, 503, 504].contains(status) || (idempotent && status == 409);
+
+/// The wait before retrying, any Retry-After or an exponential backoff.
+Duration _retryDelay(int attempt, String? retryAfter) {
+  final seconds = int.tryParse(retryAfter ?? '') ?? 0;
+  if (seconds > 0) return Duration(seconds: seconds);
+  return Duration(milliseconds: (min(250 * pow(2, attempt), 10000) * (0.5 + _random.nextDouble() / 2)).round());
+}
+
+void _addQueryParameter(Map<String, List<String>> out, String key, dynamic value) {
+  if (value == null) return;
+  if (value is Map) {
+    value.forEach((k, v) => _addQueryParameter(out, '$key[$k]', v));
+  } else if (value is Iterable) {
+    for (final v in value) {
+      _addQueryParameter(out, key, v);
+    }
+  } else {
+    out.putIfAbsent(key, () => []).add(value.toString());
+  }
+}
+
+`
+
+func GenerateDartClient(cfg DartClientConfig) error {
+	err := xrpc.WriteFile(cfg.Output, renderDartClient(cfg))
+	if err != nil {
+		return err
+	}
+
+	if cfg.PostHook != nil {
+		cfg.PostHook()
+	}
+
+	return nil
+}
+
+func renderDartClient(cfg DartClientConfig) string {
+	var sb strings.Builder
+	name := clientName(cfg.Spec)
+
+	sb.WriteString(fmt.Sprintf("// Generated xRPC client for %s.\n\n", cfg.Spec.Name))
+	sb.WriteString("import 'dart:convert';\nimport 'dart:math';\n\nimport 'package:http/http.dart' as http;\n\n")
+
+	for _, descriptor := range collectStructs(cfg.Spec) {
+		dartClass(&sb, descriptor)
+	}
+
+	sb.WriteString(dartClientRuntime)
+	sb.WriteString(commentLines("/// ", serverNotes(cfg.Spec.Server)))
+
+	sb.WriteString(fmt.Sprintf(`class %s {
+  final Uri baseUrl;
+  final Map<String, String> headers;
+
+  /// Queries and idempotent mutations are retried this many times after
+  /// network errors and 408, 429 or 5xx responses. Retried mutations reuse
+  /// their Idempotency-Key.
+  final int retries;
+  final http.Client _http;
+
+  %s({
+    String baseUrl = %s,
+    Map<String, String>? headers,
+    this.retries = 0,
+    http.Client? httpClient,
+  })  : baseUrl = Uri.parse(baseUrl),
+        headers = headers ?? {},
+        _http = httpClient ?? http.Client();
+
+  Future<dynamic> _request(String method, String path, {Map<String, dynamic>? query, Object? body, bool idempotent = false}) async {
+    final queryParameters = <String, List<String>>{};
+    query?.forEach((key, value) => _addQueryParameter(queryParameters, key, value));
+
+    final uri = baseUrl.resolve(path).replace(queryParameters: queryParameters.isEmpty ? null : queryParameters);
+    final requestHeaders = {'Accept': 'application/json', 'X-Request-ID': _newRequestId(), ...headers};
+    if (idempotent) requestHeaders['Idempotency-Key'] = _newRequestId();
+    if (body != null) requestHeaders['Content-Type'] = 'application/json';
+    final encoded = body == null ? null : jsonEncode(body);
+    final maxRetries = method == 'GET' || idempotent ? retries : 0;
+
+    for (var attempt = 0;; attempt++) {
+      final request = http.Request(method, uri)..headers.addAll(requestHeaders);
+      if (encoded != null) request.body = encoded;
+
+      final http.Response response;
+      try {
+        response = await http.Response.fromStream(await _http.send(request));
+      } on http.ClientException {
+        if (attempt >= maxRetries) rethrow;
+        await Future.delayed(_retryDelay(attempt, null));
+        continue;
+      }
+      if (attempt < maxRetries && _isRetryable(response.statusCode, idempotent)) {
+        await Future.delayed(_retryDelay(attempt, response.headers['retry-after']));
+        continue;
+      }
+
+      final decoded = response.body.isEmpty ? null : jsonDecode(response.body);
+      if (response.statusCode > 399) {
+        final envelope = decoded is Map<String, dynamic> ? decoded : null;
+        throw XRPCException(
+          response.statusCode,
+          envelope != null ? envelope['detail'] : decoded,
+          envelope?['request_id'] as String? ?? response.headers['x-request-id'],
+        );
+      }
+
+      return decoded;
+    }
+  }
+
+  void close() => _http.close();
+`, name, name, dartString(cfg.Spec.ServerUrl)))
+
+	for _, procedure := range cfg.Spec.Procedures {
+		inputType := dartSyntax.descriptor(procedure.Input)
+		output := descriptorGoType(procedure.Output)
+
+		sb.WriteString(fmt.Sprintf(
+			"\n  Future<%s> %s(%s input) async {\n", dartSyntax.spell(output), lo.CamelCase(procedure.Path), inputType,
+		))
+		if procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
+			sb.WriteString(fmt.Sprintf("    final json = await _request('GET', %s, query: input.toJson());\n", dartString(procedure.Path)))
+		} else {
+			sb.WriteString(fmt.Sprintf(
+				"    final json = await _request('POST', %s, body: input.toJson()%s);\n",
+				dartString(procedure.Path), lo.Ternary(procedure.Idempotent, ", idempotent: true", ""),
+			))
+		}
+		sb.WriteString(fmt.Sprintf("    return %s;\n  }\n", dartDecode("json", output, false)))
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
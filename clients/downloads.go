@@ -0,0 +1,50 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/struckchure/xrpc"
+	"github.com/struckchure/xrpc/internals"
+)
+
+// Downloads returns a generator per client file, for serving from the running
+// server:
+//
+//	xrpc.NewXRPC(xrpc.XRPCConfig{Explorer: true, Clients: clients.Downloads()})
+func Downloads() map[string]xrpc.ClientGenerator {
+	return map[string]xrpc.ClientGenerator{
+		"client.ts": func(spec xrpc.TRPCSpec) (string, error) {
+			file := &internals.TSFile{}
+			addTSClient(file, spec, tsTransportFetch)
+
+			return file.Render(), nil
+		},
+		"client.go": func(spec xrpc.TRPCSpec) (string, error) {
+			var buf bytes.Buffer
+			err := renderGolangClient(GolangClientConfig{Spec: spec, Mode: GolangClientModeStdlib}).Render(&buf)
+
+			return buf.String(), err
+		},
+		"client.py": func(spec xrpc.TRPCSpec) (string, error) {
+			return renderPythonClient(PythonClientConfig{Spec: spec}), nil
+		},
+		"client.dart": func(spec xrpc.TRPCSpec) (string, error) {
+			return renderDartClient(DartClientConfig{Spec: spec}), nil
+		},
+		"client.kt": func(spec xrpc.TRPCSpec) (string, error) {
+			return renderKotlinClient(KotlinClientConfig{Spec: spec}), nil
+		},
+		"client.swift": func(spec xrpc.TRPCSpec) (string, error) {
+			return renderSwiftClient(SwiftClientConfig{Spec: spec}), nil
+		},
+		"client.rs": func(spec xrpc.TRPCSpec) (string, error) {
+			return renderRustClient(RustClientConfig{Spec: spec}), nil
+		},
+		"openapi.json": func(spec xrpc.TRPCSpec) (string, error) {
+			out, err := json.MarshalIndent(renderOpenAPISpec(OpenAPIConfig{Spec: spec}), "", "  ")
+
+			return string(out), err
+		},
+	}
+}
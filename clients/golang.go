@@ -1,6 +1,7 @@
 package clients
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/dave/jennifer/jen"
@@ -8,29 +9,217 @@ import (
 	"github.com/struckchure/xrpc"
 )
 
+const restyPkg = "github.com/go-resty/resty/v2"
+
+type GolangClientMode string
+
+const (
+	// GolangClientModeResty generates a client built on github.com/go-resty/resty/v2.
+	GolangClientModeResty GolangClientMode = "resty"
+	// GolangClientModeStdlib generates a client that only depends on net/http
+	// and encoding/json.
+	GolangClientModeStdlib GolangClientMode = "stdlib"
+)
+
 type GolangClientConfig struct {
-	Spec     xrpc.TRPCSpec
-	PkgName  string
-	Output   string
+	Spec    xrpc.TRPCSpec
+	PkgName string
+	Output  string
+	Mode    GolangClientMode
+	// Tracing injects the trace context of each call's ctx into its request
+	// headers, through the propagator registered with
+	// otel.SetTextMapPropagator, so server spans join the caller's trace.
+	Tracing  bool
 	PostHook func()
 }
 
+// golangQualifiedTypes maps package-qualified types found in field descriptors
+// to their import paths.
+var golangQualifiedTypes = map[string]string{
+	"time.Time":       "time",
+	"time.Duration":   "time",
+	"json.RawMessage": "encoding/json",
+}
+
+// golangType converts a reflect type string such as "[]*main.Post" into a
+// type usable from the generated package.
+func golangType(goType string) *jen.Statement {
+	switch {
+	case strings.HasPrefix(goType, "*"):
+		return jen.Op("*").Add(golangType(goType[1:]))
+	case strings.HasPrefix(goType, "[]"):
+		return jen.Index().Add(golangType(goType[2:]))
+	case strings.HasPrefix(goType, "map["):
+		key, value := splitMapType(goType)
+		return jen.Map(golangType(key)).Add(golangType(value))
+	case goType == "interface {}":
+		return jen.Any()
+	}
+
+	if pkg, exists := golangQualifiedTypes[goType]; exists {
+		return jen.Qual(pkg, goType[strings.LastIndex(goType, ".")+1:])
+	}
+
+	// User types are declared in the generated file, so drop the qualifier.
+	return jen.Id(goType[strings.LastIndex(goType, ".")+1:])
+}
+
+// golangTypes declares Go structs for descriptors, including nested ones,
+// once per generated file.
+type golangTypes struct {
+	f        *jen.File
+	declared []string
+}
+
+func (g *golangTypes) fields(fields []xrpc.FieldDescriptor) []jen.Code {
+	return lo.Map(
+		fields,
+		func(field xrpc.FieldDescriptor, _ int) jen.Code {
+			if field.Struct != nil {
+				g.declare(*field.Struct)
+			}
+			if field.Enum != nil {
+				g.declareEnum(*field.Enum)
+			}
+
+			stmt := jen.Null()
+			for _, line := range golangFieldDoc(field) {
+				stmt.Comment(line).Line()
+			}
+
+			if field.Nillable {
+				stmt.Id(field.Name).Op("*").Add(golangType(fieldType(field)))
+			} else {
+				stmt.Id(field.Name).Add(golangType(fieldType(field)))
+			}
+
+			if field.Alias != "" {
+				stmt.Tag(map[string]string{"json": field.Alias})
+			}
+
+			return stmt
+		},
+	)
+}
+
+// declareEnum declares a string type with a constant per value, e.g.
+// StatusDraft for "draft".
+func (g *golangTypes) declareEnum(enum xrpc.EnumDescriptor) {
+	if lo.Contains(g.declared, enum.TypeName) {
+		return
+	}
+	g.declared = append(g.declared, enum.TypeName)
+
+	g.f.Line()
+	g.f.Type().Id(enum.TypeName).String()
+	g.f.Const().DefsFunc(func(defs *jen.Group) {
+		for i, value := range enum.Values {
+			name := enum.TypeName + lo.PascalCase(value)
+			if name == enum.TypeName {
+				name = fmt.Sprintf("%sValue%d", enum.TypeName, i)
+			}
+			defs.Id(name).Id(enum.TypeName).Op("=").Lit(value)
+		}
+	})
+}
+
+func golangFieldDoc(field xrpc.FieldDescriptor) []string {
+	lines := []string{}
+	if field.Description != "" {
+		lines = append(lines, strings.Split(field.Description, "\n")...)
+	}
+	if field.Format != "" {
+		lines = append(lines, "Format: "+field.Format)
+	}
+	if field.Example != "" {
+		lines = append(lines, "Example: "+field.Example)
+	}
+
+	return lines
+}
+
+// declare returns the type name of the descriptor, declaring it when needed.
+// Array descriptors return their element type name.
+func (g *golangTypes) declare(descriptor xrpc.TypeDescriptor) string {
+	if descriptor.Array != nil {
+		return g.declare(*descriptor.Array)
+	}
+
+	if descriptor.TypeName == "" || descriptor.TypeName == "nil" || goToTSType[descriptor.TypeName] != "" ||
+		lo.Contains(g.declared, descriptor.TypeName) {
+		return descriptor.TypeName
+	}
+	g.declared = append(g.declared, descriptor.TypeName)
+
+	fields := g.fields(descriptor.Fields)
+
+	g.f.Line()
+	g.f.Type().Id(descriptor.TypeName).Struct(fields...)
+
+	return descriptor.TypeName
+}
+
+// spell returns the Go type of an input or output, declaring the structs it
+// uses. Types the descriptor spells, like maps and named scalars, are used as
+// is.
+func (g *golangTypes) spell(descriptor xrpc.TypeDescriptor) *jen.Statement {
+	switch {
+	case descriptor.Array != nil:
+		return jen.Index().Add(g.spell(*descriptor.Array))
+	case descriptor.Type != "":
+		return golangType(descriptor.Type)
+	case descriptor.TypeName == "nil":
+		return jen.Any()
+	}
+
+	return jen.Id(g.declare(descriptor))
+}
+
 func GenerateGolangClient(cfg GolangClientConfig) error {
+	err := renderGolangClient(cfg).Save(cfg.Output)
+	if err != nil {
+		return err
+	}
+
+	if cfg.PostHook != nil {
+		cfg.PostHook()
+	}
+
+	return nil
+}
+
+func renderGolangClient(cfg GolangClientConfig) *jen.File {
 	cfg.Spec.Name = strings.ToLower(strings.Join(strings.Split(cfg.Spec.Name, " "), "_"))
 	if len(cfg.PkgName) == 0 {
 		cfg.PkgName = cfg.Spec.Name
 	}
+	if cfg.Mode == "" {
+		cfg.Mode = GolangClientModeResty
+	}
 
 	clientName := lo.PascalCase(cfg.Spec.Name) + "Client"
 	f := jen.NewFile(cfg.PkgName)
 
-	f.ImportAlias("github.com/go-resty/resty/v2", "resty")
+	if notes := serverNotes(cfg.Spec.Server); len(notes) > 0 {
+		f.Comment(clientName + " calls " + cfg.Spec.ServerUrl + ".")
+		f.Comment("")
+		for _, note := range notes {
+			f.Comment(note)
+		}
+	}
+	if cfg.Mode == GolangClientModeStdlib {
+		generateStdlibClient(f, clientName)
+	} else {
+		f.ImportAlias(restyPkg, "resty")
 
-	f.Type().Id(clientName).Struct(
-		jen.Id("client").Op("*").Qual("github.com/go-resty/resty/v2", "Client"),
-	)
+		f.Type().Id(clientName).Struct(
+			jen.Id("client").Op("*").Qual(restyPkg, "Client"),
+		)
+	}
 
-	// Define the structToQueryParams function
+	// Define the structToQueryParams function. Values are decoded with
+	// UseNumber so large integers survive, slices become repeated keys and
+	// nested objects use bracket notation, e.g. filter[author]=1.
 	f.Func().Id("structToQueryParams").Params(jen.Id("input").Any()).Params(jen.String(), jen.Error()).Block(
 		jen.List(jen.Id("data"), jen.Id("err")).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("input")),
 		jen.If(jen.Id("err").Op("!=").Nil()).Block(
@@ -38,186 +227,587 @@ func GenerateGolangClient(cfg GolangClientConfig) error {
 		),
 
 		jen.Var().Id("mapData").Map(jen.String()).Any(),
-		jen.Id("err").Op("=").Qual("encoding/json", "Unmarshal").Call(jen.Id("data"), jen.Op("&").Id("mapData")),
-		jen.If(jen.Id("err").Op("!=").Nil()).Block(
+		jen.Id("decoder").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("data"))),
+		jen.Id("decoder").Dot("UseNumber").Call(),
+		jen.If(jen.Err().Op(":=").Id("decoder").Dot("Decode").Call(jen.Op("&").Id("mapData")), jen.Err().Op("!=").Nil()).Block(
 			jen.Return(jen.Lit(""), jen.Qual("fmt", "Errorf").Call(jen.Lit("failed to unmarshal JSON: %w"), jen.Id("err"))),
 		),
 
 		jen.Id("query").Op(":=").Qual("net/url", "Values").Values(),
 		jen.For(jen.List(jen.Id("key"), jen.Id("value")).Op(":=").Range().Id("mapData")).Block(
-			jen.If(jen.Id("value").Op("==").Nil()).Block(
-				jen.Continue(),
-			),
-
-			jen.Id("strVal").Op(":=").Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id("value")),
-			jen.Id("query").Dot("Add").Call(jen.Id("key"), jen.Id("strVal")),
+			jen.Id("addQueryValue").Call(jen.Id("query"), jen.Id("key"), jen.Id("value")),
 		),
 
 		jen.Return(jen.Id("query").Dot("Encode").Call(), jen.Nil()),
 	)
 
-	// Define MapError type
-	f.Type().Id("MapError").Map(jen.String()).Any()
-
-	// Define Error() method
-	f.Func().Params(jen.Id("m").Id("MapError")).Id("Error").Params().String().Block(
-		jen.List(jen.Id("data"), jen.Id("err")).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("m")),
-		jen.If(jen.Id("err").Op("!=").Nil()).Block(
-			jen.Return(jen.Qual("fmt", "Sprintf").Call(jen.Lit("failed to marshal error map: %v"), jen.Id("err"))),
+	f.Line()
+	f.Func().Id("addQueryValue").Params(
+		jen.Id("query").Qual("net/url", "Values"), jen.Id("key").String(), jen.Id("value").Any(),
+	).Block(
+		jen.Switch(jen.Id("value").Op(":=").Id("value").Assert(jen.Type())).Block(
+			jen.Case(jen.Nil()).Block(),
+			jen.Case(jen.Map(jen.String()).Any()).Block(
+				jen.For(jen.List(jen.Id("k"), jen.Id("v")).Op(":=").Range().Id("value")).Block(
+					jen.Id("addQueryValue").Call(jen.Id("query"), jen.Id("key").Op("+").Lit("[").Op("+").Id("k").Op("+").Lit("]"), jen.Id("v")),
+				),
+			),
+			jen.Case(jen.Index().Any()).Block(
+				jen.For(jen.List(jen.Id("_"), jen.Id("v")).Op(":=").Range().Id("value")).Block(
+					jen.Id("addQueryValue").Call(jen.Id("query"), jen.Id("key"), jen.Id("v")),
+				),
+			),
+			jen.Default().Block(
+				jen.Id("query").Dot("Add").Call(jen.Id("key"), jen.Qual("fmt", "Sprint").Call(jen.Id("value"))),
+			),
 		),
-		jen.Return(jen.String().Call(jen.Id("data"))),
 	)
 
-	types := []string{}
+	f.Comment("XRPCError is returned for non-2xx responses, with the server's {\"detail\": ...}")
+	f.Comment("envelope decoded, or the raw body as Detail when it is not JSON.")
+	f.Type().Id("XRPCError").Struct(
+		jen.Id("StatusCode").Int().Tag(map[string]string{"json": "-"}),
+		jen.Id("Detail").Any().Tag(map[string]string{"json": "detail"}),
+		jen.Comment("RequestID is the request_id of the envelope, or the X-Request-ID header."),
+		jen.Id("RequestID").String().Tag(map[string]string{"json": "request_id"}),
+	)
 
-	getFields := func(fields []xrpc.FieldDescriptor) []jen.Code {
-		return lo.Map(
-			fields,
-			func(field xrpc.FieldDescriptor, _ int) jen.Code {
-				var stmt *jen.Statement
-				if field.Nillable {
-					stmt = jen.Id(field.Name).Op("*").Id(field.Type)
-				} else {
-					stmt = jen.Id(field.Name).Id(field.Type)
-				}
+	f.Func().Params(jen.Id("e").Op("*").Id("XRPCError")).Id("Error").Params().String().Block(
+		jen.Return(jen.Qual("fmt", "Sprintf").Call(jen.Lit("xrpc: request failed with status %d: %v"), jen.Id("e").Dot("StatusCode"), jen.Id("e").Dot("Detail"))),
+	)
 
-				if field.Alias != "" {
-					stmt.Tag(map[string]string{"json": field.Alias})
-				}
+	f.Comment("newXRPCError reads the error envelope of a failed response.")
+	f.Func().Id("newXRPCError").Params(
+		jen.Id("status").Int(), jen.Id("header").Qual("net/http", "Header"), jen.Id("body").Index().Byte(),
+	).Op("*").Id("XRPCError").Block(
+		jen.Id("xrpcErr").Op(":=").Op("&").Id("XRPCError").Values(jen.Dict{
+			jen.Id("StatusCode"): jen.Id("status"),
+			jen.Id("RequestID"):  jen.Id("header").Dot("Get").Call(jen.Lit("X-Request-ID")),
+		}),
+		jen.If(
+			jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("body"), jen.Id("xrpcErr")),
+			jen.Err().Op("!=").Nil().Op("||").Id("xrpcErr").Dot("Detail").Op("==").Nil(),
+		).Block(
+			jen.Id("xrpcErr").Dot("Detail").Op("=").String().Call(jen.Id("body")),
+		),
+		jen.Return(jen.Id("xrpcErr")),
+	)
 
-				return stmt
-			},
-		)
-	}
-	generateReturnStatement := func(output xrpc.TypeDescriptor, outputTypeName string) *jen.Statement {
-		return jen.Return().List(
-			jen.Id("resp").Dot("Result").Call().Assert(
-				lo.
-					If(output.Array != nil, jen.Op("*").Index().Id(outputTypeName)).
-					Else(jen.Op("*").Id(outputTypeName)),
-			),
-			jen.Nil(),
-		)
-	}
+	types := &golangTypes{f: f}
 
 	for _, procedure := range cfg.Spec.Procedures {
 		methodName := lo.PascalCase(strings.Join(strings.Split(procedure.Path, "/"), "_"))
 		input := procedure.Input
 		output := procedure.Output
 
-		outputTypeName := output.TypeName
+		resultType := types.spell(output)
+		inputType := types.spell(input)
 
-		if output.TypeName != "" && !lo.Contains(types, output.TypeName) {
-			f.Type().Id(output.TypeName).Struct(getFields(output.Array.Fields)...)
-			f.Line()
+		f.Line()
+		if procedure.Description != "" || procedure.Deprecation != nil {
+			f.Comment(methodName + " calls " + procedure.Path + ".")
+			if procedure.Description != "" {
+				f.Comment("")
+				for _, line := range strings.Split(procedure.Description, "\n") {
+					f.Comment(line)
+				}
+			}
+			if procedure.Deprecation != nil {
+				f.Comment("")
+				f.Comment("Deprecated: " + deprecationNotice(procedure.Deprecation))
+			}
+		}
+		_method := f.Func().Params(jen.Id("c").Op("*").Id(clientName)).Id(methodName).
+			Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("input").Add(inputType)).
+			Params(jen.Op("*").Add(resultType.Clone()), jen.Error())
 
-			types = append(types, output.TypeName)
+		if cfg.Mode == GolangClientModeStdlib {
+			_method.Block(stdlibMethodBody(procedure, resultType)...)
+		} else {
+			_method.Block(restyMethodBody(procedure, resultType)...)
 		}
+	}
 
-		if output.TypeName == "" && output.Array != nil {
-			f.Line()
-			f.Type().Id(output.Array.TypeName).Struct(getFields(output.Array.Fields)...)
-			f.Line()
+	generateGolangOptions(f, cfg.Spec.ServerUrl, cfg.Tracing)
 
-			types = append(types, output.Array.TypeName)
-			outputTypeName = output.Array.TypeName
-		}
+	f.Line()
+	if cfg.Mode == GolangClientModeStdlib {
+		generateStdlibConstructor(f, clientName, cfg.Spec.ServerUrl, cfg.Tracing)
+	} else {
+		generateRestyConstructor(f, clientName, cfg.Spec.ServerUrl, cfg.Tracing)
+	}
 
-		if input.TypeName != "" && !lo.Contains(types, input.TypeName) {
-			f.Line()
-			f.Type().Id(input.TypeName).Struct(getFields(input.Fields)...)
-			f.Line()
+	return f
+}
 
-			types = append(types, input.TypeName)
+func restyMethodBody(procedure xrpc.XRPCSpecProcedure, resultType *jen.Statement) []jen.Code {
+	request := jen.Id("c.client").
+		Dot("R").Call().
+		Dot("SetContext").Call(jen.Id("ctx"))
+
+	body := []jen.Code{jen.Var().Id("result").Add(resultType.Clone())}
+	if procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
+		body = append(body,
+			jen.List(
+				jen.Id("queryParams"),
+				jen.Err(),
+			).Op(":=").Id("structToQueryParams").Call(jen.Id("input")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return().List(jen.Nil(), jen.Err()),
+			),
+		)
+		request = request.Dot("SetQueryString").Call(jen.Id("queryParams"))
+	} else {
+		if procedure.Idempotent {
+			body = append(body,
+				jen.List(jen.Id("idempotencyKey"), jen.Err()).Op(":=").Id("randomID").Call(),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Return().List(jen.Nil(), jen.Err()),
+				),
+			)
+			request = request.
+				Dot("SetHeader").Call(jen.Lit("Idempotency-Key"), jen.Id("idempotencyKey")).
+				Dot("AddRetryCondition").Call(jen.Id("retryIdempotent"))
 		}
+		request = request.Dot("SetBody").Call(jen.Id("input"))
+	}
 
-		_method := f.Func().Params(jen.Id("c").Op("*").Id(clientName)).Id(methodName).
-			Params(jen.Id("input").Id(input.TypeName))
+	request = request.
+		Dot("SetResult").Call(jen.Op("&").Id("result"))
 
-		if output.Array != nil {
-			_method.Params(jen.Op("*").Index().Id(outputTypeName), jen.Error())
-		} else {
-			_method.Params(jen.Op("*").Id(outputTypeName), jen.Error())
-		}
+	if procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
+		request = request.Dot("Get").Call(jen.Lit(procedure.Path))
+	} else {
+		request = request.Dot("Post").Call(jen.Lit(procedure.Path))
+	}
 
-		if procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
-			_method.Block(
-				jen.List(
-					jen.Id("queryParams"),
-					jen.Err(),
-				).Op(":=").Id("structToQueryParams").Call(jen.Id("input")),
-				jen.If(jen.Err().Op("!=").Nil()).Block(
-					jen.Return().List(jen.Nil(), jen.Err()),
+	return append(body,
+		jen.List(
+			jen.Id("resp"),
+			jen.Err(),
+		).Op(":=").Add(request),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return().List(jen.Nil(), jen.Err()),
+		),
+		jen.Line(),
+		jen.If(jen.Id("resp").Dot("IsError").Call()).Block(
+			jen.Return().List(
+				jen.Nil(),
+				jen.Id("newXRPCError").Call(jen.Id("resp").Dot("StatusCode").Call(), jen.Id("resp").Dot("Header").Call(), jen.Id("resp").Dot("Body").Call()),
+			),
+		),
+		jen.Return().List(jen.Op("&").Id("result"), jen.Nil()),
+	)
+}
+
+func generateRestyConstructor(f *jen.File, clientName string, serverUrl string, tracing bool) {
+	f.Comment("retryQuery retries queries that may succeed when retried.")
+	f.Func().Id("retryQuery").Params(jen.Id("resp").Op("*").Qual(restyPkg, "Response"), jen.Err().Error()).Bool().Block(
+		jen.Return(
+			jen.Id("resp").Op("!=").Nil().Op("&&").Id("resp").Dot("Request").Dot("Method").Op("==").Qual("net/http", "MethodGet").Op("&&").
+				Parens(jen.Err().Op("!=").Nil().Op("||").Id("retryableStatus").Call(jen.Id("resp").Dot("StatusCode").Call(), jen.False())),
+		),
+	)
+
+	f.Line()
+	f.Comment("retryIdempotent retries idempotent mutations that may succeed when retried.")
+	f.Func().Id("retryIdempotent").Params(jen.Id("resp").Op("*").Qual(restyPkg, "Response"), jen.Err().Error()).Bool().Block(
+		jen.Return(
+			jen.Id("resp").Op("!=").Nil().Op("&&").
+				Parens(jen.Err().Op("!=").Nil().Op("||").Id("retryableStatus").Call(jen.Id("resp").Dot("StatusCode").Call(), jen.True())),
+		),
+	)
+
+	f.Line()
+	f.Func().Id("New"+clientName).Params(jen.Id("opts").Op("...").Id("Option")).Op("*").Id(clientName).Block(
+		newClientOptions(serverUrl, tracing),
+		jen.Line(),
+		jen.Id("client").Op(":=").Qual(restyPkg, "New").Call(),
+		jen.If(jen.Id("o").Dot("httpClient").Op("!=").Nil()).Block(
+			jen.Id("client").Op("=").Qual(restyPkg, "NewWithClient").Call(jen.Id("o").Dot("httpClient")),
+		),
+		jen.Id("client").Dot("SetBaseURL").Call(jen.Id("o").Dot("baseURL")),
+		jen.Id("client").Dot("SetHeaders").Call(jen.Id("o").Dot("headers")),
+		jen.If(jen.Id("o").Dot("authToken").Op("!=").Lit("")).Block(
+			jen.Id("client").Dot("SetAuthToken").Call(jen.Id("o").Dot("authToken")),
+		),
+		jen.If(jen.Id("o").Dot("timeout").Op(">").Lit(0)).Block(
+			jen.Id("client").Dot("SetTimeout").Call(jen.Id("o").Dot("timeout")),
+		),
+		jen.If(jen.Id("o").Dot("retries").Op(">").Lit(0)).Block(
+			jen.Id("client").
+				Dot("SetRetryCount").Call(jen.Id("o").Dot("retries")).
+				Dot("SetRetryMaxWaitTime").Call(jen.Lit(30).Op("*").Qual("time", "Second")).
+				Dot("SetRetryAfter").Call(
+				jen.Func().Params(jen.Id("_").Op("*").Qual(restyPkg, "Client"), jen.Id("resp").Op("*").Qual(restyPkg, "Response")).
+					Params(jen.Qual("time", "Duration"), jen.Error()).
+					Block(jen.Return(jen.Id("retryAfter").Call(jen.Id("resp").Dot("Header").Call()), jen.Nil())),
+			).
+				Dot("AddRetryCondition").Call(jen.Id("retryQuery")),
+		),
+		jen.Id("client").Dot("SetPreRequestHook").Call(
+			jen.Func().Params(jen.Id("_").Op("*").Qual(restyPkg, "Client"), jen.Id("r").Op("*").Qual("net/http", "Request")).Error().Block(
+				jen.For(jen.List(jen.Id("_"), jen.Id("interceptor")).Op(":=").Range().Id("o").Dot("interceptors")).Block(
+					jen.If(jen.Err().Op(":=").Id("interceptor").Call(jen.Id("r")), jen.Err().Op("!=").Nil()).Block(
+						jen.Return(jen.Err()),
+					),
 				),
-				jen.List(
-					jen.Id("resp"),
-					jen.Err(),
-				).Op(":=").Id("c.client").
-					Dot("R").Call().
-					Dot("SetQueryString").Call(jen.Id("queryParams")).
-					Dot("SetError").Call(jen.Op("&").Id("MapError").Values()).
-					Dot("SetResult").Call(
-					lo.
-						If(output.Array != nil, jen.Op("&").Index().Id(outputTypeName).Values()).
-						Else(jen.Op("&").Id(outputTypeName).Values()),
-				).
-					Dot("Get").Call(jen.Lit(procedure.Path)),
-				jen.If(jen.Err().Op("!=").Nil()).Block(
-					jen.Return().List(jen.Nil(), jen.Err()),
+				jen.Return(jen.Nil()),
+			),
+		),
+		jen.Line(),
+		jen.Return(jen.Op("&").Id(clientName).Values(jen.Dict{
+			jen.Id("client"): jen.Id("client"),
+		})),
+	)
+}
+
+func generateStdlibClient(f *jen.File, clientName string) {
+	f.Type().Id(clientName).Struct(
+		jen.Id("baseURL").String(),
+		jen.Id("httpClient").Op("*").Qual("net/http", "Client"),
+		jen.Id("headers").Map(jen.String()).String(),
+		jen.Id("interceptors").Index().Id("RequestInterceptor"),
+		jen.Id("retries").Int(),
+	)
+
+	// do sends a JSON request, retrying queries and idempotent mutations, and
+	// decodes either the result or an *XRPCError, matching the resty
+	// client's behaviour.
+	f.Line()
+	f.Func().Params(jen.Id("c").Op("*").Id(clientName)).Id("do").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.List(jen.Id("method"), jen.Id("path"), jen.Id("query")).String(),
+		jen.List(jen.Id("body"), jen.Id("result")).Any(),
+		jen.Id("idempotent").Bool(),
+	).Error().Block(
+		jen.Var().Id("data").Index().Byte(),
+		jen.If(jen.Id("body").Op("!=").Nil()).Block(
+			jen.Var().Err().Error(),
+			jen.List(jen.Id("data"), jen.Err()).Op("=").Qual("encoding/json", "Marshal").Call(jen.Id("body")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Qual("fmt", "Errorf").Call(jen.Lit("failed to marshal input: %w"), jen.Err())),
+			),
+		),
+		jen.Line(),
+		jen.Id("endpoint").Op(":=").Qual("strings", "TrimSuffix").Call(jen.Id("c").Dot("baseURL"), jen.Lit("/")).Op("+").Id("path"),
+		jen.If(jen.Id("query").Op("!=").Lit("")).Block(
+			jen.Id("endpoint").Op("+=").Lit("?").Op("+").Id("query"),
+		),
+		jen.Line(),
+		jen.Id("headers").Op(":=").Map(jen.String()).String().Values(jen.Dict{jen.Lit("Accept"): jen.Lit("application/json")}),
+		jen.If(jen.Id("body").Op("!=").Nil()).Block(
+			jen.Id("headers").Index(jen.Lit("Content-Type")).Op("=").Lit("application/json"),
+		),
+		jen.For(jen.List(jen.Id("key"), jen.Id("value")).Op(":=").Range().Id("c").Dot("headers")).Block(
+			jen.Id("headers").Index(jen.Id("key")).Op("=").Id("value"),
+		),
+		jen.If(jen.Id("idempotent")).Block(
+			jen.List(jen.Id("key"), jen.Err()).Op(":=").Id("randomID").Call(),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Err()),
+			),
+			jen.Id("headers").Index(jen.Lit("Idempotency-Key")).Op("=").Id("key"),
+		),
+		jen.Id("retries").Op(":=").Lit(0),
+		jen.If(jen.Id("method").Op("==").Qual("net/http", "MethodGet").Op("||").Id("idempotent")).Block(
+			jen.Id("retries").Op("=").Id("c").Dot("retries"),
+		),
+		jen.Line(),
+		jen.For(jen.Id("attempt").Op(":=").Lit(0), jen.Empty(), jen.Id("attempt").Op("++")).Block(
+			jen.List(jen.Id("req"), jen.Err()).Op(":=").Qual("net/http", "NewRequestWithContext").Call(
+				jen.Id("ctx"), jen.Id("method"), jen.Id("endpoint"), jen.Qual("bytes", "NewReader").Call(jen.Id("data")),
+			),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Err()),
+			),
+			jen.For(jen.List(jen.Id("key"), jen.Id("value")).Op(":=").Range().Id("headers")).Block(
+				jen.Id("req").Dot("Header").Dot("Set").Call(jen.Id("key"), jen.Id("value")),
+			),
+			jen.For(jen.List(jen.Id("_"), jen.Id("interceptor")).Op(":=").Range().Id("c").Dot("interceptors")).Block(
+				jen.If(jen.Err().Op(":=").Id("interceptor").Call(jen.Id("req")), jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Err()),
 				),
-				jen.Line(),
-				jen.If(jen.Id("resp").Dot("IsError").Call()).Block(
-					jen.Return().List(
-						jen.Nil(),
-						jen.Id("resp").Dot("Error").Call().Assert(jen.Op("*").Id("MapError")),
+			),
+			jen.Line(),
+			jen.List(jen.Id("resp"), jen.Err()).Op(":=").Id("c").Dot("httpClient").Dot("Do").Call(jen.Id("req")),
+			jen.If(
+				jen.Id("attempt").Op("<").Id("retries").Op("&&").
+					Parens(jen.Err().Op("!=").Nil().Op("||").Id("retryableStatus").Call(jen.Id("resp").Dot("StatusCode"), jen.Id("idempotent"))),
+			).Block(
+				jen.Id("wait").Op(":=").Id("backoff").Call(jen.Id("attempt")),
+				jen.If(jen.Id("resp").Op("!=").Nil()).Block(
+					jen.If(jen.Id("after").Op(":=").Id("retryAfter").Call(jen.Id("resp").Dot("Header")), jen.Id("after").Op(">").Lit(0)).Block(
+						jen.Id("wait").Op("=").Id("after"),
 					),
+					jen.Id("resp").Dot("Body").Dot("Close").Call(),
 				),
-				generateReturnStatement(output, outputTypeName),
-			)
-		} else if procedure.Type == xrpc.XRPCSpecProcedureTypeMutation {
-			_method.Block(
-				jen.List(
-					jen.Id("resp"),
-					jen.Err(),
-				).Op(":=").Id("c.client").
-					Dot("R").Call().
-					Dot("SetBody").Call(jen.Id("input")).
-					Dot("SetError").Call(jen.Op("&").Id("MapError").Values()).
-					Dot("SetResult").Call(jen.Op("&").Id(outputTypeName).Values()).
-					Dot("Post").Call(jen.Lit(procedure.Path)),
-				jen.If(jen.Err().Op("!=").Nil()).Block(
-					jen.Return().List(jen.Nil(), jen.Err()),
-				),
-				jen.Line(),
-				jen.If(jen.Id("resp").Dot("IsError").Call()).Block(
-					jen.Return().List(
-						jen.Nil(),
-						jen.Id("resp").Dot("Error").Call().Assert(jen.Op("*").Id("MapError")),
+				jen.Select().Block(
+					jen.Case(jen.Op("<-").Qual("time", "After").Call(jen.Id("wait"))).Block(
+						jen.Continue(),
+					),
+					jen.Case(jen.Op("<-").Id("ctx").Dot("Done").Call()).Block(
+						jen.Return(jen.Id("ctx").Dot("Err").Call()),
 					),
 				),
-				generateReturnStatement(output, outputTypeName),
-			)
-		}
-	}
+			),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Err()),
+			),
+			jen.Line(),
+			jen.Return(jen.Id("decodeResponse").Call(jen.Id("resp"), jen.Id("result"))),
+		),
+	)
 
 	f.Line()
-	f.Func().Id("New"+clientName).Params().Op("*").Id(clientName).Block(
-		jen.Id("client").Op(":=").Qual("github.com/go-resty/resty/v2", "New").Call(),
-		jen.Id("client").Dot("SetBaseURL").Call(jen.Lit(cfg.Spec.ServerUrl)),
+	f.Func().Id("decodeResponse").Params(
+		jen.Id("resp").Op("*").Qual("net/http", "Response"),
+		jen.Id("result").Any(),
+	).Error().Block(
+		jen.Defer().Id("resp").Dot("Body").Dot("Close").Call(),
+		jen.Line(),
+		jen.If(jen.Id("resp").Dot("StatusCode").Op(">").Lit(399)).Block(
+			jen.List(jen.Id("body"), jen.Err()).Op(":=").Qual("io", "ReadAll").Call(jen.Id("resp").Dot("Body")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Err()),
+			),
+			jen.Return(jen.Id("newXRPCError").Call(jen.Id("resp").Dot("StatusCode"), jen.Id("resp").Dot("Header"), jen.Id("body"))),
+		),
+		jen.Line(),
+		jen.Return(jen.Qual("encoding/json", "NewDecoder").Call(jen.Id("resp").Dot("Body")).Dot("Decode").Call(jen.Id("result"))),
+	)
+
+	f.Line()
+	f.Comment("backoff waits exponentially longer between attempts, with jitter.")
+	f.Func().Id("backoff").Params(jen.Id("attempt").Int()).Qual("time", "Duration").Block(
+		jen.Id("wait").Op(":=").Lit(250).Op("*").Qual("time", "Millisecond").Op("<<").Min(jen.Id("attempt"), jen.Lit(5)),
+		jen.Return(jen.Id("wait").Op("/").Lit(2).Op("+").Qual("time", "Duration").Call(
+			jen.Qual("math/rand", "Int63n").Call(jen.Int64().Call(jen.Id("wait").Op("/").Lit(2))),
+		)),
+	)
+	f.Line()
+}
+
+func stdlibMethodBody(procedure xrpc.XRPCSpecProcedure, resultType *jen.Statement) []jen.Code {
+	body := []jen.Code{}
+	call := jen.Id("c").Dot("do")
+
+	if procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
+		body = append(body,
+			jen.List(
+				jen.Id("queryParams"),
+				jen.Err(),
+			).Op(":=").Id("structToQueryParams").Call(jen.Id("input")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return().List(jen.Nil(), jen.Err()),
+			),
+		)
+		call = call.Call(jen.Id("ctx"), jen.Qual("net/http", "MethodGet"), jen.Lit(procedure.Path), jen.Id("queryParams"), jen.Nil(), jen.Op("&").Id("result"), jen.False())
+	} else {
+		call = call.Call(jen.Id("ctx"), jen.Qual("net/http", "MethodPost"), jen.Lit(procedure.Path), jen.Lit(""), jen.Id("input"), jen.Op("&").Id("result"), jen.Lit(procedure.Idempotent))
+	}
+
+	return append(body,
+		jen.Var().Id("result").Add(resultType.Clone()),
+		jen.If(jen.Err().Op(":=").Add(call), jen.Err().Op("!=").Nil()).Block(
+			jen.Return().List(jen.Nil(), jen.Err()),
+		),
+		jen.Return().List(jen.Op("&").Id("result"), jen.Nil()),
+	)
+}
+
+func generateStdlibConstructor(f *jen.File, clientName string, serverUrl string, tracing bool) {
+	f.Func().Id("New"+clientName).Params(jen.Id("opts").Op("...").Id("Option")).Op("*").Id(clientName).Block(
+		newClientOptions(serverUrl, tracing),
+		jen.Line(),
+		jen.Id("httpClient").Op(":=").Op("&").Qual("net/http", "Client").Values(),
+		jen.If(jen.Id("o").Dot("httpClient").Op("!=").Nil()).Block(
+			jen.Id("clone").Op(":=").Op("*").Id("o").Dot("httpClient"),
+			jen.Id("httpClient").Op("=").Op("&").Id("clone"),
+		),
+		jen.If(jen.Id("o").Dot("timeout").Op(">").Lit(0)).Block(
+			jen.Id("httpClient").Dot("Timeout").Op("=").Id("o").Dot("timeout"),
+		),
+		jen.If(jen.Id("o").Dot("authToken").Op("!=").Lit("")).Block(
+			jen.Id("o").Dot("headers").Index(jen.Lit("Authorization")).Op("=").Lit("Bearer ").Op("+").Id("o").Dot("authToken"),
+		),
 		jen.Line(),
 		jen.Return(jen.Op("&").Id(clientName).Values(jen.Dict{
-			jen.Id("client"): jen.Id("client"),
+			jen.Id("baseURL"):      jen.Id("o").Dot("baseURL"),
+			jen.Id("httpClient"):   jen.Id("httpClient"),
+			jen.Id("headers"):      jen.Id("o").Dot("headers"),
+			jen.Id("interceptors"): jen.Id("o").Dot("interceptors"),
+			jen.Id("retries"):      jen.Id("o").Dot("retries"),
 		})),
 	)
+}
 
-	err := f.Save(cfg.Output)
-	if err != nil {
-		return err
+func newClientOptions(serverUrl string, tracing bool) jen.Code {
+	interceptors := []jen.Code{jen.Id("setRequestID")}
+	if tracing {
+		interceptors = append(interceptors, jen.Id("injectTraceContext"))
 	}
 
-	if cfg.PostHook != nil {
-		cfg.PostHook()
+	return jen.Add(
+		jen.Id("o").Op(":=").Op("&").Id("clientOptions").Values(jen.Dict{
+			jen.Id("baseURL"):      jen.Lit(serverUrl),
+			jen.Id("headers"):      jen.Map(jen.String()).String().Values(),
+			jen.Id("interceptors"): jen.Index().Id("RequestInterceptor").Values(interceptors...),
+		}),
+		jen.Line(),
+		jen.For(jen.List(jen.Id("_"), jen.Id("opt")).Op(":=").Range().Id("opts")).Block(
+			jen.Id("opt").Call(jen.Id("o")),
+		),
+	)
+}
+
+// generateGolangOptions emits the functional options accepted by the generated
+// client constructor.
+func generateGolangOptions(f *jen.File, serverUrl string, tracing bool) {
+	f.Line()
+	f.Comment("RequestInterceptor can inspect or modify every outgoing request.")
+	f.Type().Id("RequestInterceptor").Func().Params(jen.Op("*").Qual("net/http", "Request")).Error()
+
+	f.Line()
+	f.Comment("randomID returns a random hex identifier, e.g. for request IDs and")
+	f.Comment("idempotency keys.")
+	f.Func().Id("randomID").Params().Params(jen.String(), jen.Error()).Block(
+		jen.Id("id").Op(":=").Make(jen.Index().Byte(), jen.Lit(16)),
+		jen.If(jen.List(jen.Id("_"), jen.Err()).Op(":=").Qual("crypto/rand", "Read").Call(jen.Id("id")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Lit(""), jen.Err()),
+		),
+		jen.Return(jen.Qual("encoding/hex", "EncodeToString").Call(jen.Id("id")), jen.Nil()),
+	)
+
+	f.Line()
+	f.Comment("setRequestID identifies each request with a random X-Request-ID, unless one")
+	f.Comment("was set, e.g. with WithHeader.")
+	f.Func().Id("setRequestID").Params(jen.Id("req").Op("*").Qual("net/http", "Request")).Error().Block(
+		jen.If(jen.Id("req").Dot("Header").Dot("Get").Call(jen.Lit("X-Request-ID")).Op("!=").Lit("")).Block(
+			jen.Return(jen.Nil()),
+		),
+		jen.Line(),
+		jen.List(jen.Id("id"), jen.Err()).Op(":=").Id("randomID").Call(),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Err()),
+		),
+		jen.Id("req").Dot("Header").Dot("Set").Call(jen.Lit("X-Request-ID"), jen.Id("id")),
+		jen.Return(jen.Nil()),
+	)
+
+	f.Line()
+	f.Comment("retryableStatus reports whether a call failing with status may succeed when")
+	f.Comment("retried, including an idempotent mutation still in flight.")
+	f.Func().Id("retryableStatus").Params(jen.Id("status").Int(), jen.Id("idempotent").Bool()).Bool().Block(
+		jen.Switch(jen.Id("status")).Block(
+			jen.Case(
+				jen.Qual("net/http", "StatusRequestTimeout"),
+				jen.Qual("net/http", "StatusTooManyRequests"),
+				jen.Qual("net/http", "StatusInternalServerError"),
+				jen.Qual("net/http", "StatusBadGateway"),
+				jen.Qual("net/http", "StatusServiceUnavailable"),
+				jen.Qual("net/http", "StatusGatewayTimeout"),
+			).Block(
+				jen.Return(jen.True()),
+			),
+		),
+		jen.Return(jen.Id("idempotent").Op("&&").Id("status").Op("==").Qual("net/http", "StatusConflict")),
+	)
+
+	f.Line()
+	f.Comment("retryAfter returns the wait requested by a Retry-After header, or zero.")
+	f.Func().Id("retryAfter").Params(jen.Id("header").Qual("net/http", "Header")).Qual("time", "Duration").Block(
+		jen.List(jen.Id("seconds"), jen.Id("_")).Op(":=").Qual("strconv", "Atoi").Call(jen.Id("header").Dot("Get").Call(jen.Lit("Retry-After"))),
+		jen.Return(jen.Qual("time", "Duration").Call(jen.Id("seconds")).Op("*").Qual("time", "Second")),
+	)
+
+	if tracing {
+		f.Line()
+		f.Comment("injectTraceContext propagates the trace of the request's context, e.g.")
+		f.Comment("a W3C traceparent header, so the server continues the caller's trace.")
+		f.Func().Id("injectTraceContext").Params(jen.Id("req").Op("*").Qual("net/http", "Request")).Error().Block(
+			jen.Qual("go.opentelemetry.io/otel", "GetTextMapPropagator").Call().Dot("Inject").Call(
+				jen.Id("req").Dot("Context").Call(),
+				jen.Qual("go.opentelemetry.io/otel/propagation", "HeaderCarrier").Call(jen.Id("req").Dot("Header")),
+			),
+			jen.Return(jen.Nil()),
+		)
 	}
 
-	return nil
+	f.Line()
+	f.Type().Id("clientOptions").Struct(
+		jen.Id("baseURL").String(),
+		jen.Id("httpClient").Op("*").Qual("net/http", "Client"),
+		jen.Id("headers").Map(jen.String()).String(),
+		jen.Id("authToken").String(),
+		jen.Id("timeout").Qual("time", "Duration"),
+		jen.Id("interceptors").Index().Id("RequestInterceptor"),
+		jen.Id("retries").Int(),
+	)
+
+	f.Line()
+	f.Comment("Option configures the client returned by the generated constructor.")
+	f.Type().Id("Option").Func().Params(jen.Op("*").Id("clientOptions"))
+
+	options := []struct {
+		name    string
+		comment string
+		params  []jen.Code
+		body    jen.Code
+	}{
+		{
+			name:    "WithBaseURL",
+			comment: "WithBaseURL overrides the server URL from the spec (" + serverUrl + ").",
+			params:  []jen.Code{jen.Id("url").String()},
+			body:    jen.Id("o").Dot("baseURL").Op("=").Id("url"),
+		},
+		{
+			name:    "WithHTTPClient",
+			comment: "WithHTTPClient sends requests through a custom *http.Client.",
+			params:  []jen.Code{jen.Id("client").Op("*").Qual("net/http", "Client")},
+			body:    jen.Id("o").Dot("httpClient").Op("=").Id("client"),
+		},
+		{
+			name:    "WithHeader",
+			comment: "WithHeader adds a header to every request.",
+			params:  []jen.Code{jen.Id("key"), jen.Id("value").String()},
+			body:    jen.Id("o").Dot("headers").Index(jen.Id("key")).Op("=").Id("value"),
+		},
+		{
+			name:    "WithAuthToken",
+			comment: "WithAuthToken sends the token as an Authorization: Bearer header.",
+			params:  []jen.Code{jen.Id("token").String()},
+			body:    jen.Id("o").Dot("authToken").Op("=").Id("token"),
+		},
+		{
+			name:    "WithTimeout",
+			comment: "WithTimeout bounds the duration of every request.",
+			params:  []jen.Code{jen.Id("timeout").Qual("time", "Duration")},
+			body:    jen.Id("o").Dot("timeout").Op("=").Id("timeout"),
+		},
+		{
+			name: "WithRetries",
+			comment: "WithRetries retries queries and idempotent mutations up to retries times after\n" +
+				"network errors and 408, 429 or 5xx responses, waiting for any Retry-After.\n" +
+				"Retried mutations reuse their Idempotency-Key.",
+			params: []jen.Code{jen.Id("retries").Int()},
+			body:   jen.Id("o").Dot("retries").Op("=").Id("retries"),
+		},
+		{
+			name:    "WithRequestInterceptor",
+			comment: "WithRequestInterceptor runs the interceptors, in order, before every request is sent.",
+			params:  []jen.Code{jen.Id("interceptors").Op("...").Id("RequestInterceptor")},
+			body:    jen.Id("o").Dot("interceptors").Op("=").Append(jen.Id("o").Dot("interceptors"), jen.Id("interceptors").Op("...")),
+		},
+	}
+
+	for _, option := range options {
+		f.Line()
+		for _, line := range strings.Split(option.comment, "\n") {
+			f.Comment(line)
+		}
+		f.Func().Id(option.name).Params(option.params...).Id("Option").Block(
+			jen.Return(jen.Func().Params(jen.Id("o").Op("*").Id("clientOptions")).Block(option.body)),
+		)
+	}
 }
@@ -0,0 +1,66 @@
+package clients
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/struckchure/xrpc"
+)
+
+type golangTestUserID int64
+
+type golangTestInput struct {
+	ID golangTestUserID `json:"id"`
+}
+
+type golangTestPost struct {
+	Title string `json:"title"`
+}
+
+func TestGolangClientCompiles(t *testing.T) {
+	specPath := filepath.Join(t.TempDir(), "xrpc.yaml")
+
+	app := xrpc.NewXRPC(xrpc.XRPCConfig{Name: "Outputs", SpecPath: specPath})
+	app.Router("outputs",
+		xrpc.NewProcedure[golangTestInput, map[string]int]("counts").Query(func(c xrpc.Context[golangTestInput, map[string]int]) error { return nil }),
+		xrpc.NewProcedure[golangTestInput, []map[string]int]("pages").Query(func(c xrpc.Context[golangTestInput, []map[string]int]) error { return nil }),
+		xrpc.NewProcedure[golangTestInput, string]("name").Query(func(c xrpc.Context[golangTestInput, string]) error { return nil }),
+		xrpc.NewProcedure[golangTestInput, golangTestUserID]("id").Mutation(func(c xrpc.Context[golangTestInput, golangTestUserID]) error { return nil }),
+		xrpc.NewProcedure[golangTestInput, *golangTestPost]("post").Query(func(c xrpc.Context[golangTestInput, *golangTestPost]) error { return nil }),
+		xrpc.NewProcedure[map[string]string, []golangTestPost]("search").Mutation(func(c xrpc.Context[map[string]string, []golangTestPost]) error { return nil }),
+	)
+	if err := app.GenerateSpec(); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := xrpc.LoadSpec(specPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The client is built as a package of this module, so it resolves resty
+	// from its go.mod.
+	if err := os.MkdirAll("testdata", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove("testdata") })
+
+	for _, mode := range []GolangClientMode{GolangClientModeStdlib, GolangClientModeResty} {
+		dir, err := os.MkdirTemp("testdata", "golang-client-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+
+		err = GenerateGolangClient(GolangClientConfig{Spec: spec, Output: filepath.Join(dir, "client.go"), PkgName: "client", Mode: mode})
+		if err != nil {
+			t.Fatalf("mode %s: %v", mode, err)
+		}
+
+		if out, err := exec.Command("go", "vet", "./"+dir).CombinedOutput(); err != nil {
+			t.Errorf("mode %s: go vet failed: %v\n%s", mode, err, out)
+		}
+	}
+}
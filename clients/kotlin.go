@@ -0,0 +1,264 @@
+package clients
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc"
+)
+
+type KotlinClientConfig struct {
+	Spec     xrpc.TRPCSpec
+	Package  string
+	Output   string
+	PostHook func()
+}
+
+var kotlinSyntax = typeSyntax{
+	primitives: map[string]string{
+		"string":          "String",
+		"int":             "Long",
+		"int8":            "Int",
+		"int16":           "Int",
+		"int32":           "Int",
+		"int64":           "Long",
+		"uint":            "Long",
+		"uint8":           "Int",
+		"uint16":          "Int",
+		"uint32":          "Long",
+		"uint64":          "Long",
+		"float32":         "Float",
+		"float64":         "Double",
+		"bool":            "Boolean",
+		"interface{}":     "JsonElement",
+		"interface {}":    "JsonElement",
+		"any":             "JsonElement",
+		"nil":             "Unit",
+		"time.Time":       "String",
+		"time.Duration":   "Long",
+		"json.RawMessage": "JsonElement",
+	},
+	array: func(elem string) string { return "List<" + elem + ">" },
+	dict:  func(key, value string) string { return "Map<" + key + ", " + value + ">" },
+	named: func(name string) string { return lo.PascalCase(name) },
+}
+
+var kotlinKeywords = []string{
+	"as", "break", "class", "continue", "do", "else", "false", "for", "fun", "if", "in", "interface",
+	"is", "null", "object", "package", "return", "super", "this", "throw", "true", "try", "typealias",
+	"typeof", "val", "var", "when", "while",
+}
+
+// kotlinString quotes s, escaping "$" which would otherwise interpolate.
+func kotlinString(s string) string {
+	return strings.ReplaceAll(strconv.Quote(s), "$", `\$`)
+}
+
+func kotlinIdentifier(alias string) string {
+	name := lo.CamelCase(alias)
+	if lo.Contains(kotlinKeywords, name) {
+		return "`" + name + "`"
+	}
+
+	return name
+}
+
+func kotlinDataClass(sb *strings.Builder, descriptor xrpc.TypeDescriptor) {
+	sb.WriteString("@Serializable\n")
+	if len(descriptor.Fields) == 0 {
+		sb.WriteString(fmt.Sprintf("class %s\n\n", kotlinSyntax.named(descriptor.TypeName)))
+		return
+	}
+
+	sb.WriteString(fmt.Sprintf("data class %s(\n", kotlinSyntax.named(descriptor.TypeName)))
+	for _, field := range descriptor.Fields {
+		t := parseGoType(stringFieldType(field))
+		typ := kotlinSyntax.spell(t)
+
+		// Go marshals nil slices and maps as null; with coerceInputValues the
+		// defaults below absorb that for non-nullable collections.
+		defaultValue := ""
+		switch {
+		case field.Nillable:
+			typ, defaultValue = typ+"?", " = null"
+		case t.kind == goTypeArray:
+			defaultValue = " = emptyList()"
+		case t.kind == goTypeMap:
+			defaultValue = " = emptyMap()"
+		}
+
+		sb.WriteString(fmt.Sprintf(
+			"    @SerialName(%s) val %s: %s%s,\n", kotlinString(field.Alias), kotlinIdentifier(field.Alias), typ, defaultValue,
+		))
+	}
+	sb.WriteString(")\n\n")
+}
+
+const kotlinClientRuntime = `/** A failed call, carrying the error body's detail, or the whole body when it has none. */
+class XRPCException(
+    val statusCode: Int,
+    val detail: JsonElement?,
+    /** Sent as X-Request-ID, and reported back by the server in the error body. */
+    val requestId: String? = null,
+) : Exception("xRPC request failed with status $statusCode: $detail") {
+    /** Field-level validation messages, when the server rejected the input. */
+    val fieldErrors: Map<String, String>?
+        get() = (detail as? JsonObject)?.mapValues { (_, value) -> (value as? JsonPrimitive)?.content ?: value.toString() }
+}
+
+/** Whether a call failing with [status] may succeed when retried. */
+private fun isRetryable(status: Int, idempotent: Boolean): Boolean =
+    status in listOf(408, 429, 500, 502, 503, 504) || (idempotent && status == 409)
+
+/** Milliseconds to wait before retrying, any Retry-After or an exponential backoff. */
+private fun retryDelay(attempt: Int, retryAfter: String?): Long {
+    val seconds = retryAfter?.toLongOrNull() ?: 0
+    if (seconds > 0) return seconds * 1000
+    return (minOf(250.0 * Math.pow(2.0, attempt.toDouble()), 10_000.0) * (0.5 + Math.random() / 2)).toLong()
+}
+
+private fun addQueryParameters(builder: HttpUrl.Builder, key: String, value: JsonElement) {
+    when (value) {
+        is JsonNull -> Unit
+        is JsonObject -> value.forEach { (k, v) -> addQueryParameters(builder, "$key[$k]", v) }
+        is JsonArray -> value.forEach { addQueryParameters(builder, key, it) }
+        is JsonPrimitive -> builder.addQueryParameter(key, value.content)
+    }
+}
+
+`
+
+func GenerateKotlinClient(cfg KotlinClientConfig) error {
+	err := xrpc.WriteFile(cfg.Output, renderKotlinClient(cfg))
+	if err != nil {
+		return err
+	}
+
+	if cfg.PostHook != nil {
+		cfg.PostHook()
+	}
+
+	return nil
+}
+
+func renderKotlinClient(cfg KotlinClientConfig) string {
+	var sb strings.Builder
+	name := clientName(cfg.Spec)
+
+	sb.WriteString(fmt.Sprintf("// Generated xRPC client for %s.\n", cfg.Spec.Name))
+	if cfg.Package != "" {
+		sb.WriteString(fmt.Sprintf("package %s\n", cfg.Package))
+	}
+	sb.WriteString(`
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.delay
+import kotlinx.coroutines.withContext
+import kotlinx.serialization.SerialName
+import kotlinx.serialization.Serializable
+import kotlinx.serialization.json.*
+import okhttp3.HttpUrl
+import okhttp3.HttpUrl.Companion.toHttpUrl
+import okhttp3.MediaType.Companion.toMediaType
+import okhttp3.OkHttpClient
+import okhttp3.Request
+import okhttp3.RequestBody.Companion.toRequestBody
+import java.io.IOException
+import java.util.UUID
+
+`)
+
+	for _, descriptor := range collectStructs(cfg.Spec) {
+		kotlinDataClass(&sb, descriptor)
+	}
+
+	sb.WriteString(kotlinClientRuntime)
+	if notes := serverNotes(cfg.Spec.Server); len(notes) > 0 {
+		sb.WriteString("/**\n" + commentLines(" * ", notes) + " */\n")
+	}
+
+	sb.WriteString(fmt.Sprintf(`class %s(
+    private val baseUrl: String = %s,
+    private val headers: Map<String, String> = emptyMap(),
+    private val httpClient: OkHttpClient = OkHttpClient(),
+    private val json: Json = Json {
+        ignoreUnknownKeys = true
+        coerceInputValues = true
+        explicitNulls = false
+    },
+    /**
+     * Queries and idempotent mutations are retried this many times after network errors and 408, 429
+     * or 5xx responses. Retried mutations reuse their Idempotency-Key.
+     */
+    private val retries: Int = 0,
+) {
+    private suspend fun request(method: String, path: String, query: JsonElement?, body: JsonElement?, idempotent: Boolean = false): JsonElement {
+        val url = (baseUrl.trimEnd('/') + path).toHttpUrl().newBuilder().apply {
+            (query as? JsonObject)?.forEach { (key, value) -> addQueryParameters(this, key, value) }
+        }.build()
+
+        val builder = Request.Builder().url(url)
+            .header("Accept", "application/json")
+            .header("X-Request-ID", UUID.randomUUID().toString())
+        headers.forEach { (key, value) -> builder.header(key, value) }
+        if (idempotent) builder.header("Idempotency-Key", UUID.randomUUID().toString())
+        builder.method(method, body?.toString()?.toRequestBody("application/json".toMediaType()))
+        val request = builder.build()
+        val maxRetries = if (method == "GET" || idempotent) retries else 0
+
+        var attempt = 0
+        while (true) {
+            val response = try {
+                withContext(Dispatchers.IO) { httpClient.newCall(request).execute() }
+            } catch (e: IOException) {
+                if (attempt >= maxRetries) throw e
+                delay(retryDelay(attempt++, null))
+                continue
+            }
+            if (attempt < maxRetries && isRetryable(response.code, idempotent)) {
+                val retryAfter = response.header("Retry-After")
+                response.close()
+                delay(retryDelay(attempt++, retryAfter))
+                continue
+            }
+
+            return withContext(Dispatchers.IO) {
+                response.use {
+                    val text = response.body?.string().orEmpty()
+                    val element = if (text.isEmpty()) JsonNull else json.parseToJsonElement(text)
+                    if (response.code > 399) {
+                        val envelope = element as? JsonObject
+                        throw XRPCException(
+                            response.code,
+                            envelope?.get("detail") ?: element,
+                            (envelope?.get("request_id") as? JsonPrimitive)?.content ?: response.header("X-Request-ID"),
+                        )
+                    }
+                    element
+                }
+            }
+        }
+    }
+`, name, kotlinString(cfg.Spec.ServerUrl)))
+
+	for _, procedure := range cfg.Spec.Procedures {
+		inputType := kotlinSyntax.descriptor(procedure.Input)
+		outputType := kotlinSyntax.descriptor(procedure.Output)
+
+		sb.WriteString(fmt.Sprintf("\n    suspend fun %s(input: %s): %s {\n", lo.CamelCase(procedure.Path), inputType, outputType))
+		if procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
+			sb.WriteString(fmt.Sprintf("        val result = request(\"GET\", %s, json.encodeToJsonElement(input), null)\n", kotlinString(procedure.Path)))
+		} else {
+			sb.WriteString(fmt.Sprintf(
+				"        val result = request(\"POST\", %s, null, json.encodeToJsonElement(input)%s)\n",
+				kotlinString(procedure.Path), lo.Ternary(procedure.Idempotent, ", idempotent = true", ""),
+			))
+		}
+		sb.WriteString("        return json.decodeFromJsonElement(result)\n    }\n")
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
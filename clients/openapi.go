@@ -0,0 +1,311 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc"
+	"gopkg.in/yaml.v3"
+)
+
+type OpenAPIConfig struct {
+	Spec xrpc.TRPCSpec
+	// Output is written as YAML when it ends in .yaml or .yml, JSON otherwise.
+	Output string
+	// Version is the API version in the document's info, defaults to 1.0.0.
+	Version  string
+	PostHook func()
+}
+
+var openAPIPrimitives = map[string]map[string]any{
+	"string":          {"type": "string"},
+	"int":             {"type": "integer", "format": "int64"},
+	"int8":            {"type": "integer", "format": "int32"},
+	"int16":           {"type": "integer", "format": "int32"},
+	"int32":           {"type": "integer", "format": "int32"},
+	"int64":           {"type": "integer", "format": "int64"},
+	"uint":            {"type": "integer", "minimum": 0},
+	"uint8":           {"type": "integer", "minimum": 0},
+	"uint16":          {"type": "integer", "minimum": 0},
+	"uint32":          {"type": "integer", "minimum": 0},
+	"uint64":          {"type": "integer", "minimum": 0},
+	"float32":         {"type": "number", "format": "float"},
+	"float64":         {"type": "number", "format": "double"},
+	"bool":            {"type": "boolean"},
+	"interface{}":     {},
+	"interface {}":    {},
+	"any":             {},
+	"nil":             {"type": "null"},
+	"time.Time":       {"type": "string", "format": "date-time"},
+	"time.Duration":   {"type": "integer", "format": "int64"},
+	"json.RawMessage": {},
+}
+
+func openAPISchema(t *goType) map[string]any {
+	switch t.kind {
+	case goTypeArray:
+		return map[string]any{"type": "array", "items": openAPISchema(t.elem)}
+	case goTypeMap:
+		return map[string]any{"type": "object", "additionalProperties": openAPISchema(t.elem)}
+	case goTypeNamed:
+		return map[string]any{"$ref": "#/components/schemas/" + lo.PascalCase(t.name)}
+	}
+
+	return openAPIPrimitives[t.name]
+}
+
+// openAPIField describes a field, with enums referenced as their own schemas.
+func openAPIField(field xrpc.FieldDescriptor) map[string]any {
+	schema := lo.Assign(openAPISchema(parseGoType(fieldType(field))))
+	if field.Description != "" {
+		schema["description"] = field.Description
+	}
+	if field.Format != "" {
+		schema["format"] = field.Format
+	}
+	if field.Example != "" {
+		schema["examples"] = []string{field.Example}
+	}
+
+	return schema
+}
+
+func openAPIObject(descriptor xrpc.TypeDescriptor) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for _, field := range descriptor.Fields {
+		properties[field.Alias] = openAPIField(field)
+		if !field.Nillable {
+			required = append(required, field.Alias)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+func openAPIContent(schema map[string]any, examples []any) map[string]any {
+	media := map[string]any{"schema": schema}
+	if len(examples) > 0 {
+		named := map[string]any{}
+		for i, example := range examples {
+			named[fmt.Sprintf("example%d", i+1)] = map[string]any{"value": example}
+		}
+		media["examples"] = named
+	}
+
+	return map[string]any{"application/json": media}
+}
+
+// openAPISecuritySchemeName names the security scheme component of an auth
+// scheme, e.g. "bearerJwt" or "apiKeyXApiKey".
+func openAPISecuritySchemeName(scheme xrpc.XRPCSpecAuthScheme) string {
+	return lo.CamelCase(strings.Join([]string{string(scheme.Type), scheme.Format, scheme.Header}, " "))
+}
+
+func openAPISecurityScheme(scheme xrpc.XRPCSpecAuthScheme) map[string]any {
+	if scheme.Type == xrpc.XRPCSpecAuthTypeAPIKey {
+		return map[string]any{"type": "apiKey", "in": "header", "name": scheme.Header}
+	}
+
+	securityScheme := map[string]any{"type": "http", "scheme": "bearer"}
+	if scheme.Format != "" {
+		securityScheme["bearerFormat"] = scheme.Format
+	}
+
+	return securityScheme
+}
+
+// openAPISecurity lists the alternative ways to meet every requirement, as
+// OpenAPI security requirements are alternatives of schemes that all apply.
+func openAPISecurity(auth []xrpc.XRPCSpecAuth) []map[string][]string {
+	alternatives := []map[string][]string{{}}
+	for _, requirement := range auth {
+		next := []map[string][]string{}
+		for _, alternative := range alternatives {
+			for _, scheme := range requirement.Schemes {
+				next = append(next, lo.Assign(alternative, map[string][]string{openAPISecuritySchemeName(scheme): {}}))
+			}
+		}
+		alternatives = next
+	}
+
+	return alternatives
+}
+
+func openAPIOperation(procedure xrpc.XRPCSpecProcedure) map[string]any {
+	operation := map[string]any{"operationId": lo.CamelCase(procedure.Path)}
+
+	if procedure.Description != "" {
+		operation["summary"] = strings.SplitN(procedure.Description, "\n", 2)[0]
+		operation["description"] = procedure.Description
+	}
+	if len(procedure.Tags) > 0 {
+		operation["tags"] = procedure.Tags
+	}
+	if procedure.Deprecation != nil {
+		operation["deprecated"] = true
+		operation["description"] = strings.TrimSpace(procedure.Description + "\n\n**Deprecated:** " + deprecationNotice(procedure.Deprecation))
+		if procedure.Deprecation.Sunset != nil {
+			operation["x-sunset"] = procedure.Deprecation.Sunset.Format("2006-01-02")
+		}
+	}
+
+	if len(procedure.Auth) > 0 {
+		operation["security"] = openAPISecurity(procedure.Auth)
+	}
+	if len(procedure.Policies) > 0 {
+		operation["x-policies"] = procedure.Policies
+	}
+
+	inputs := lo.Map(procedure.Examples, func(example xrpc.XRPCSpecExample, _ int) any { return example.Input })
+	outputs := lo.Map(procedure.Examples, func(example xrpc.XRPCSpecExample, _ int) any { return example.Output })
+
+	parameters := []map[string]any{}
+	if procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
+		// Inputs are sent as query parameters: arrays repeat the key and
+		// nested objects use brackets, e.g. filter[author]=1.
+		parameters = lo.Map(procedure.Input.Fields, func(field xrpc.FieldDescriptor, _ int) map[string]any {
+			t := parseGoType(fieldType(field))
+			parameter := map[string]any{
+				"name":     field.Alias,
+				"in":       "query",
+				"required": !field.Nillable,
+				"schema":   openAPIField(field),
+			}
+			if t.kind == goTypeNamed || t.kind == goTypeMap {
+				parameter["style"] = "deepObject"
+				parameter["explode"] = true
+			}
+
+			return parameter
+		})
+	} else {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  openAPIContent(openAPISchema(descriptorGoType(procedure.Input)), inputs),
+		}
+	}
+	if procedure.Idempotent {
+		parameters = append(parameters, map[string]any{
+			"name":        "Idempotency-Key",
+			"in":          "header",
+			"description": "Repeating a key replays the response of its first call.",
+			"schema":      map[string]any{"type": "string"},
+		})
+	}
+
+	responses := map[string]any{
+		"200": map[string]any{
+			"description": "OK",
+			"content":     openAPIContent(openAPISchema(descriptorGoType(procedure.Output)), outputs),
+		},
+		"default": map[string]any{
+			"description": "The {\"detail\": ...} error envelope.",
+			"content":     openAPIContent(map[string]any{"$ref": "#/components/schemas/XRPCError"}, nil),
+		},
+	}
+	if procedure.Cache != nil {
+		operation["x-cache"] = procedure.Cache
+		parameters = append(parameters, map[string]any{
+			"name":        "If-None-Match",
+			"in":          "header",
+			"description": "The ETag of a cached response, answered with a 304 while it is current.",
+			"schema":      map[string]any{"type": "string"},
+		})
+		responses["304"] = map[string]any{"description": "Not Modified"}
+	}
+
+	if len(parameters) > 0 || procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
+		operation["parameters"] = parameters
+	}
+	operation["responses"] = responses
+
+	return operation
+}
+
+// renderOpenAPISpec describes the spec as an OpenAPI 3.1 document.
+func renderOpenAPISpec(cfg OpenAPIConfig) map[string]any {
+	schemas := map[string]any{
+		"XRPCError": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"detail":     map[string]any{},
+				"request_id": map[string]any{"type": "string", "description": "The X-Request-ID of the failed request."},
+			},
+		},
+	}
+	for _, descriptor := range collectStructs(cfg.Spec) {
+		schemas[lo.PascalCase(descriptor.TypeName)] = openAPIObject(descriptor)
+	}
+	for _, enum := range collectEnums(cfg.Spec) {
+		schemas[lo.PascalCase(enum.TypeName)] = map[string]any{"type": "string", "enum": enum.Values}
+	}
+
+	paths := map[string]any{}
+	securitySchemes := map[string]any{}
+	for _, procedure := range cfg.Spec.Procedures {
+		method := lo.Ternary(procedure.Type == xrpc.XRPCSpecProcedureTypeQuery, "get", "post")
+		paths[procedure.Path] = map[string]any{method: openAPIOperation(procedure)}
+
+		for _, requirement := range procedure.Auth {
+			for _, scheme := range requirement.Schemes {
+				securitySchemes[openAPISecuritySchemeName(scheme)] = openAPISecurityScheme(scheme)
+			}
+		}
+	}
+
+	components := map[string]any{"schemas": schemas}
+	if len(securitySchemes) > 0 {
+		components["securitySchemes"] = securitySchemes
+	}
+
+	info := map[string]any{"title": cfg.Spec.Name, "version": lo.CoalesceOrEmpty(cfg.Version, "1.0.0")}
+	if notes := serverNotes(cfg.Spec.Server); len(notes) > 0 {
+		info["description"] = strings.Join(notes, "\n\n")
+	}
+
+	return map[string]any{
+		"openapi":    "3.1.0",
+		"info":       info,
+		"servers":    []map[string]any{{"url": cfg.Spec.ServerUrl}},
+		"paths":      paths,
+		"components": components,
+	}
+}
+
+func GenerateOpenAPISpec(cfg OpenAPIConfig) error {
+	var (
+		out []byte
+		err error
+	)
+
+	document := renderOpenAPISpec(cfg)
+	switch strings.ToLower(filepath.Ext(cfg.Output)) {
+	case ".yaml", ".yml":
+		out, err = yaml.Marshal(document)
+	default:
+		out, err = json.MarshalIndent(document, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal OpenAPI document: %w", err)
+	}
+
+	err = xrpc.WriteFile(cfg.Output, string(out))
+	if err != nil {
+		return err
+	}
+
+	if cfg.PostHook != nil {
+		cfg.PostHook()
+	}
+
+	return nil
+}
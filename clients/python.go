@@ -0,0 +1,236 @@
+package clients
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc"
+)
+
+type PythonClientConfig struct {
+	Spec     xrpc.TRPCSpec
+	Output   string
+	PostHook func()
+}
+
+var pythonSyntax = typeSyntax{
+	primitives: map[string]string{
+		"string":          "str",
+		"int":             "int",
+		"int8":            "int",
+		"int16":           "int",
+		"int32":           "int",
+		"int64":           "int",
+		"uint":            "int",
+		"uint8":           "int",
+		"uint16":          "int",
+		"uint32":          "int",
+		"uint64":          "int",
+		"float32":         "float",
+		"float64":         "float",
+		"bool":            "bool",
+		"interface{}":     "Any",
+		"interface {}":    "Any",
+		"any":             "Any",
+		"nil":             "None",
+		"time.Time":       "str",
+		"time.Duration":   "int",
+		"json.RawMessage": "Any",
+	},
+	array: func(elem string) string { return "List[" + elem + "]" },
+	dict:  func(key, value string) string { return "Dict[" + key + ", " + value + "]" },
+	named: func(name string) string { return lo.PascalCase(name) },
+}
+
+var pythonIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var pythonKeywords = []string{
+	"False", "None", "True", "and", "as", "assert", "async", "await", "break", "class", "continue",
+	"def", "del", "elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in",
+	"is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try", "while", "with", "yield",
+}
+
+func pythonFieldType(field xrpc.FieldDescriptor) string {
+	if field.Nillable {
+		return "NotRequired[Optional[" + pythonSyntax.convert(fieldType(field)) + "]]"
+	}
+
+	return pythonSyntax.convert(fieldType(field))
+}
+
+// pythonTypedDict declares a TypedDict, falling back to the functional syntax
+// when a JSON key is not a valid Python identifier.
+func pythonTypedDict(sb *strings.Builder, descriptor xrpc.TypeDescriptor) {
+	name := pythonSyntax.named(descriptor.TypeName)
+
+	classSyntax := lo.EveryBy(descriptor.Fields, func(field xrpc.FieldDescriptor) bool {
+		return pythonIdentifier.MatchString(field.Alias) && !lo.Contains(pythonKeywords, field.Alias)
+	})
+
+	if !classSyntax {
+		sb.WriteString(fmt.Sprintf("%s = TypedDict(\n    %q,\n    {\n", name, name))
+		for _, field := range descriptor.Fields {
+			sb.WriteString(fmt.Sprintf("        %q: %q,\n", field.Alias, pythonFieldType(field)))
+		}
+		sb.WriteString("    },\n)\n\n\n")
+		return
+	}
+
+	sb.WriteString(fmt.Sprintf("class %s(TypedDict):\n", name))
+	if len(descriptor.Fields) == 0 {
+		sb.WriteString("    pass\n")
+	}
+	for _, field := range descriptor.Fields {
+		sb.WriteString(fmt.Sprintf("    %s: %s\n", field.Alias, pythonFieldType(field)))
+	}
+	sb.WriteString("\n\n")
+}
+
+const pythonClientRuntime = `class XRPCError(Exception):
+    """An error response; detail is the "detail" of the JSON body, or the raw text."""
+
+    def __init__(self, status: int, detail: Any, request_id: Optional[str] = None) -> None:
+        super().__init__(f"xRPC request failed with status {status}: {detail!r}")
+        self.status = status
+        self.detail = detail
+        # Quote request_id when reporting the failure; the server logs each call with it.
+        self.request_id = request_id
+
+
+def _query_items(key: str, value: Any) -> List[Tuple[str, str]]:
+    if value is None:
+        return []
+    if isinstance(value, bool):
+        return [(key, "true" if value else "false")]
+    if isinstance(value, dict):
+        return [item for k, v in value.items() for item in _query_items(f"{key}[{k}]", v)]
+    if isinstance(value, (list, tuple)):
+        return [item for v in value for item in _query_items(key, v)]
+    return [(key, str(value))]
+
+
+def _retryable(status: int, idempotent: bool) -> bool:
+    """Whether a call failing with status may succeed when retried."""
+    return status in (408, 429, 500, 502, 503, 504) or (idempotent and status == 409)
+
+
+def _retry_delay(attempt: int, retry_after: Optional[str] = None) -> float:
+    """Seconds to wait before retrying, any Retry-After or an exponential backoff."""
+    if retry_after and retry_after.isdigit() and int(retry_after) > 0:
+        return float(retry_after)
+    return min(0.25 * 2**attempt, 10.0) * (0.5 + random.random() / 2)
+
+
+`
+
+func GeneratePythonClient(cfg PythonClientConfig) error {
+	err := xrpc.WriteFile(cfg.Output, renderPythonClient(cfg))
+	if err != nil {
+		return err
+	}
+
+	if cfg.PostHook != nil {
+		cfg.PostHook()
+	}
+
+	return nil
+}
+
+func renderPythonClient(cfg PythonClientConfig) string {
+	var sb strings.Builder
+	name := clientName(cfg.Spec)
+
+	sb.WriteString(fmt.Sprintf("\"\"\"Generated xRPC client for %s.\"\"\"\n\n", cfg.Spec.Name))
+	sb.WriteString("from __future__ import annotations\n\n")
+	sb.WriteString("import itertools\nimport json\nimport random\nimport time\nimport urllib.error\nimport urllib.parse\nimport urllib.request\nimport uuid\n")
+	sb.WriteString("from typing import Any, Dict, List, Literal, Optional, Tuple, TypedDict\n\n")
+	sb.WriteString("try:\n    from typing import NotRequired\nexcept ImportError:  # Python < 3.11\n    from typing_extensions import NotRequired\n\n\n")
+
+	for _, enum := range collectEnums(cfg.Spec) {
+		values := lo.Map(enum.Values, func(value string, _ int) string { return strconv.Quote(value) })
+		sb.WriteString(fmt.Sprintf("%s = Literal[%s]\n\n\n", pythonSyntax.named(enum.TypeName), strings.Join(values, ", ")))
+	}
+
+	for _, descriptor := range collectStructs(cfg.Spec) {
+		pythonTypedDict(&sb, descriptor)
+	}
+
+	sb.WriteString(pythonClientRuntime)
+
+	sb.WriteString(fmt.Sprintf("class %s:\n", name))
+	if notes := serverNotes(cfg.Spec.Server); len(notes) > 0 {
+		sb.WriteString("    \"\"\"" + strings.Join(notes, "\n    ") + "\n    \"\"\"\n\n")
+	}
+	sb.WriteString(fmt.Sprintf(`    def __init__(
+        self,
+        base_url: str = %q,
+        headers: Optional[Dict[str, str]] = None,
+        timeout: Optional[float] = None,
+        retries: int = 0,
+    ) -> None:
+        self.base_url = base_url.rstrip("/")
+        self.headers = dict(headers or {})
+        self.timeout = timeout
+        # Queries and idempotent mutations are retried this many times after
+        # network errors and 408, 429 or 5xx responses. Retried mutations reuse
+        # their Idempotency-Key.
+        self.retries = retries
+
+    def _request(self, method: str, path: str, query: Any = None, body: Any = None, idempotent: bool = False) -> Any:
+        url = self.base_url + path
+        if query:
+            items = [item for k, v in query.items() for item in _query_items(k, v)]
+            url += "?" + urllib.parse.urlencode(items)
+
+        headers = {"Accept": "application/json", "X-Request-ID": uuid.uuid4().hex, **self.headers}
+        if idempotent:
+            headers["Idempotency-Key"] = uuid.uuid4().hex
+        data = None
+        if body is not None:
+            data = json.dumps(body).encode("utf-8")
+            headers["Content-Type"] = "application/json"
+
+        retries = self.retries if method == "GET" or idempotent else 0
+        for attempt in itertools.count():
+            request = urllib.request.Request(url, data=data, headers=headers, method=method)
+            try:
+                with urllib.request.urlopen(request, timeout=self.timeout) as response:
+                    return json.loads(response.read() or b"null")
+            except urllib.error.HTTPError as error:
+                if attempt < retries and _retryable(error.code, idempotent):
+                    time.sleep(_retry_delay(attempt, error.headers.get("Retry-After")))
+                    continue
+                payload = error.read()
+                request_id = error.headers.get("X-Request-ID")
+                try:
+                    envelope = json.loads(payload)
+                    detail = envelope.get("detail")
+                    request_id = envelope.get("request_id") or request_id
+                except (ValueError, AttributeError):
+                    detail = payload.decode("utf-8", "replace")
+                raise XRPCError(error.code, detail, request_id) from None
+            except OSError:
+                if attempt >= retries:
+                    raise
+                time.sleep(_retry_delay(attempt))
+`, cfg.Spec.ServerUrl))
+
+	for _, procedure := range cfg.Spec.Procedures {
+		inputType := pythonSyntax.descriptor(procedure.Input)
+		outputType := pythonSyntax.descriptor(procedure.Output)
+
+		sb.WriteString(fmt.Sprintf("\n    def %s(self, input: %s) -> %s:\n", lo.SnakeCase(procedure.Path), inputType, outputType))
+		if procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
+			sb.WriteString(fmt.Sprintf("        return self._request(\"GET\", %q, query=input)\n", procedure.Path))
+		} else {
+			sb.WriteString(fmt.Sprintf(
+				"        return self._request(\"POST\", %q, body=input%s)\n", procedure.Path, lo.Ternary(procedure.Idempotent, ", idempotent=True", ""),
+			))
+		}
+	}
+
+	return sb.String()
+}
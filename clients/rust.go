@@ -0,0 +1,373 @@
+package clients
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc"
+)
+
+type RustClientConfig struct {
+	Spec     xrpc.TRPCSpec
+	Output   string
+	PostHook func()
+}
+
+var rustSyntax = typeSyntax{
+	primitives: map[string]string{
+		"string":          "String",
+		"int":             "i64",
+		"int8":            "i8",
+		"int16":           "i16",
+		"int32":           "i32",
+		"int64":           "i64",
+		"uint":            "u64",
+		"uint8":           "u8",
+		"uint16":          "u16",
+		"uint32":          "u32",
+		"uint64":          "u64",
+		"float32":         "f32",
+		"float64":         "f64",
+		"bool":            "bool",
+		"interface{}":     "serde_json::Value",
+		"interface {}":    "serde_json::Value",
+		"any":             "serde_json::Value",
+		"nil":             "serde_json::Value",
+		"time.Time":       "String",
+		"time.Duration":   "i64",
+		"json.RawMessage": "serde_json::Value",
+	},
+	array: func(elem string) string { return "Vec<" + elem + ">" },
+	dict:  func(key, value string) string { return "HashMap<" + key + ", " + value + ">" },
+	named: func(name string) string { return lo.PascalCase(name) },
+}
+
+var rustKeywords = []string{
+	"as", "async", "await", "break", "const", "continue", "crate", "dyn", "else", "enum", "extern",
+	"false", "fn", "for", "if", "impl", "in", "let", "loop", "match", "mod", "move", "mut", "pub",
+	"ref", "return", "static", "struct", "trait", "true", "type", "unsafe", "use", "where", "while",
+}
+
+func rustIdentifier(alias string) string {
+	name := lo.SnakeCase(alias)
+	if lo.Contains(rustKeywords, name) {
+		return "r#" + name
+	}
+
+	return name
+}
+
+// rustStruct declares the struct, boxing the fields in recursive, which would
+// otherwise give it an infinite size.
+func rustStruct(sb *strings.Builder, descriptor xrpc.TypeDescriptor, recursive map[string]bool) {
+	sb.WriteString("#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]\n")
+	sb.WriteString(fmt.Sprintf("pub struct %s {\n", rustSyntax.named(descriptor.TypeName)))
+	for _, field := range descriptor.Fields {
+		t := parseGoType(stringFieldType(field))
+		typ := rustSyntax.spell(t)
+		if recursive[descriptor.TypeName+"."+field.Name] {
+			typ = "Box<" + typ + ">"
+		}
+
+		attrs := []string{"rename = " + strconv.Quote(field.Alias)}
+		switch {
+		case field.Nillable:
+			typ = "Option<" + typ + ">"
+			attrs = append(attrs, "default", `skip_serializing_if = "Option::is_none"`)
+		case t.kind == goTypeArray || t.kind == goTypeMap:
+			// Go marshals nil slices and maps as null.
+			attrs = append(attrs, "default", `deserialize_with = "null_as_default"`)
+		}
+
+		sb.WriteString(fmt.Sprintf("    #[serde(%s)]\n", strings.Join(attrs, ", ")))
+		sb.WriteString(fmt.Sprintf("    pub %s: %s,\n", rustIdentifier(field.Alias), typ))
+	}
+	sb.WriteString("}\n\n")
+}
+
+// rustNullAsDefault is only emitted when a struct field deserializes with it,
+// so clients compile without dead code warnings.
+const rustNullAsDefault = `fn null_as_default<'de, D, T>(deserializer: D) -> Result<T, D::Error>
+where
+    D: serde::Deserializer<'de>,
+    T: Default + Deserialize<'de>,
+{
+    Ok(Option::<T>::deserialize(deserializer)?.unwrap_or_default())
+}
+
+`
+
+const rustClientRuntime = `/// Errors returned by the generated client.
+#[derive(Debug)]
+pub enum Error {
+    /// The request could not be sent or the response could not be read.
+    Http(reqwest::Error),
+    /// The input or response body was not valid JSON for the expected type.
+    Json(serde_json::Error),
+    /// The server answered with a non-2xx status. request_id is the id the
+    /// server logged the call under.
+    Api {
+        status: u16,
+        detail: serde_json::Value,
+        request_id: Option<String>,
+    },
+}
+
+impl fmt::Display for Error {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Error::Http(err) => write!(f, "xRPC request failed: {err}"),
+            Error::Json(err) => write!(f, "xRPC payload could not be decoded: {err}"),
+            Error::Api { status, detail, .. } => write!(f, "xRPC request failed with status {status}: {detail}"),
+        }
+    }
+}
+
+impl std::error::Error for Error {}
+
+impl From<reqwest::Error> for Error {
+    fn from(err: reqwest::Error) -> Self {
+        Error::Http(err)
+    }
+}
+
+impl From<serde_json::Error> for Error {
+    fn from(err: serde_json::Error) -> Self {
+        Error::Json(err)
+    }
+}
+
+/// Returns a random id, e.g. for request ids and idempotency keys, without
+/// depending on a uuid or rand crate.
+fn new_request_id() -> String {
+    use std::hash::{BuildHasher, Hasher};
+
+    let nanos = SystemTime::now().duration_since(UNIX_EPOCH).map(|d| d.as_nanos()).unwrap_or_default();
+    (0..2)
+        .map(|i| {
+            let mut hasher = RandomState::new().build_hasher();
+            hasher.write_u128(nanos);
+            hasher.write_u8(i);
+            format!("{:016x}", hasher.finish())
+        })
+        .collect()
+}
+
+/// Whether a call failing with status may succeed when retried.
+fn is_retryable(status: u16, idempotent: bool) -> bool {
+    matches!(status, 408 | 429 | 500 | 502 | 503 | 504) || (idempotent && status == 409)
+}
+
+/// The wait before retrying, any Retry-After or an exponential backoff.
+fn retry_delay(attempt: u32, retry_after: Option<u64>) -> Duration {
+    use std::hash::{BuildHasher, Hasher};
+
+    match retry_after {
+        Some(seconds) if seconds > 0 => Duration::from_secs(seconds),
+        _ => {
+            let jitter = 0.5 + (RandomState::new().build_hasher().finish() % 500) as f64 / 1000.0;
+            Duration::from_millis(((250u64 << attempt.min(6)).min(10_000) as f64 * jitter) as u64)
+        }
+    }
+}
+
+fn query_pairs(pairs: &mut Vec<(String, String)>, key: String, value: serde_json::Value) {
+    match value {
+        serde_json::Value::Null => {}
+        serde_json::Value::String(value) => pairs.push((key, value)),
+        serde_json::Value::Array(values) => {
+            for value in values {
+                query_pairs(pairs, key.clone(), value);
+            }
+        }
+        serde_json::Value::Object(fields) => {
+            for (k, value) in fields {
+                query_pairs(pairs, format!("{key}[{k}]"), value);
+            }
+        }
+        value => pairs.push((key, value.to_string())),
+    }
+}
+
+`
+
+func GenerateRustClient(cfg RustClientConfig) error {
+	err := xrpc.WriteFile(cfg.Output, renderRustClient(cfg))
+	if err != nil {
+		return err
+	}
+
+	if cfg.PostHook != nil {
+		cfg.PostHook()
+	}
+
+	return nil
+}
+
+func renderRustClient(cfg RustClientConfig) string {
+	var sb strings.Builder
+	name := clientName(cfg.Spec)
+
+	sb.WriteString(fmt.Sprintf("//! Generated xRPC client for %s.\n", cfg.Spec.Name))
+	sb.WriteString("//!\n//! Requires the `reqwest` (with the `json` feature), `serde` (with `derive`),\n//! `serde_json` and `tokio` (with `time`) crates.\n\n")
+	// Structs are rendered first, so only the imports and helpers they use
+	// are emitted.
+	var structs strings.Builder
+	declared := collectStructs(cfg.Spec)
+	recursive := recursiveFields(declared)
+	for _, descriptor := range declared {
+		rustStruct(&structs, descriptor, recursive)
+	}
+
+	sb.WriteString("use std::collections::hash_map::RandomState;\n")
+	if strings.Contains(structs.String(), "HashMap<") {
+		sb.WriteString("use std::collections::HashMap;\n")
+	}
+	sb.WriteString("use std::fmt;\nuse std::time::{Duration, SystemTime, UNIX_EPOCH};\n\n")
+	sb.WriteString("use reqwest::header::{HeaderMap, HeaderName, HeaderValue};\n")
+	sb.WriteString("use serde::de::DeserializeOwned;\nuse serde::{Deserialize, Serialize};\n\n")
+
+	sb.WriteString(structs.String())
+	if strings.Contains(structs.String(), `"null_as_default"`) {
+		sb.WriteString(rustNullAsDefault)
+	}
+	sb.WriteString(rustClientRuntime)
+	sb.WriteString(commentLines("/// ", serverNotes(cfg.Spec.Server)))
+
+	sb.WriteString(fmt.Sprintf(`#[derive(Debug, Clone)]
+pub struct %s {
+    base_url: String,
+    http: reqwest::Client,
+    headers: HeaderMap,
+    retries: u32,
+}
+
+impl Default for %s {
+    fn default() -> Self {
+        Self::new(%s)
+    }
+}
+
+impl %s {
+    pub fn new(base_url: impl Into<String>) -> Self {
+        Self {
+            base_url: base_url.into(),
+            http: reqwest::Client::new(),
+            headers: HeaderMap::new(),
+            retries: 0,
+        }
+    }
+
+    /// Sends requests through a preconfigured reqwest client.
+    pub fn with_http_client(mut self, http: reqwest::Client) -> Self {
+        self.http = http;
+        self
+    }
+
+    /// Adds a header to every request.
+    pub fn with_header(mut self, name: HeaderName, value: HeaderValue) -> Self {
+        self.headers.insert(name, value);
+        self
+    }
+
+    /// Retries queries and idempotent mutations this many times after network
+    /// errors and 408, 429 or 5xx responses, waiting for any Retry-After.
+    /// Retried mutations reuse their Idempotency-Key.
+    pub fn with_retries(mut self, retries: u32) -> Self {
+        self.retries = retries;
+        self
+    }
+
+    async fn request<I, O>(&self, method: reqwest::Method, path: &str, input: &I, idempotent: bool) -> Result<O, Error>
+    where
+        I: Serialize + ?Sized,
+        O: DeserializeOwned,
+    {
+        let url = format!("{}{}", self.base_url.trim_end_matches('/'), path);
+        let mut builder = self
+            .http
+            .request(method.clone(), url)
+            .header("X-Request-ID", new_request_id())
+            .headers(self.headers.clone());
+        if idempotent {
+            builder = builder.header("Idempotency-Key", new_request_id());
+        }
+
+        if method == reqwest::Method::GET {
+            let mut pairs = Vec::new();
+            if let serde_json::Value::Object(fields) = serde_json::to_value(input)? {
+                for (key, value) in fields {
+                    query_pairs(&mut pairs, key, value);
+                }
+            }
+            builder = builder.query(&pairs);
+        } else {
+            builder = builder.json(input);
+        }
+
+        let request = builder.build()?;
+        let retries = if method == reqwest::Method::GET || idempotent { self.retries } else { 0 };
+
+        let mut attempt = 0;
+        let response = loop {
+            let retry = request.try_clone().expect("JSON request bodies can be cloned");
+            match self.http.execute(retry).await {
+                Ok(response) if attempt < retries && is_retryable(response.status().as_u16(), idempotent) => {
+                    let retry_after = response
+                        .headers()
+                        .get("Retry-After")
+                        .and_then(|value| value.to_str().ok())
+                        .and_then(|value| value.parse().ok());
+                    tokio::time::sleep(retry_delay(attempt, retry_after)).await;
+                }
+                Err(_) if attempt < retries => tokio::time::sleep(retry_delay(attempt, None)).await,
+                result => break result?,
+            }
+            attempt += 1;
+        };
+        let status = response.status().as_u16();
+        let mut request_id = response
+            .headers()
+            .get("X-Request-ID")
+            .and_then(|value| value.to_str().ok())
+            .map(String::from);
+        let body = response.bytes().await?;
+
+        if status > 399 {
+            let detail = match serde_json::from_slice::<serde_json::Value>(&body) {
+                Ok(serde_json::Value::Object(mut envelope)) if envelope.contains_key("detail") => {
+                    if let Some(serde_json::Value::String(id)) = envelope.remove("request_id") {
+                        request_id = Some(id);
+                    }
+                    envelope.remove("detail").unwrap_or_default()
+                }
+                Ok(value) => value,
+                Err(_) => serde_json::Value::String(String::from_utf8_lossy(&body).into_owned()),
+            };
+            return Err(Error::Api { status, detail, request_id });
+        }
+
+        Ok(serde_json::from_slice(&body)?)
+    }
+`, name, name, strconv.Quote(cfg.Spec.ServerUrl), name))
+
+	for _, procedure := range cfg.Spec.Procedures {
+		method := lo.Ternary(procedure.Type == xrpc.XRPCSpecProcedureTypeQuery, "GET", "POST")
+
+		sb.WriteString(fmt.Sprintf(
+			"\n    pub async fn %s(&self, input: &%s) -> Result<%s, Error> {\n        self.request(reqwest::Method::%s, %s, input, %t).await\n    }\n",
+			lo.SnakeCase(procedure.Path),
+			rustSyntax.descriptor(procedure.Input),
+			rustSyntax.descriptor(procedure.Output),
+			method,
+			strconv.Quote(procedure.Path),
+			procedure.Idempotent,
+		))
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
@@ -0,0 +1,368 @@
+package clients
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc"
+)
+
+type SwiftClientConfig struct {
+	Spec     xrpc.TRPCSpec
+	Output   string
+	PostHook func()
+}
+
+var swiftSyntax = typeSyntax{
+	primitives: map[string]string{
+		"string":          "String",
+		"int":             "Int",
+		"int8":            "Int8",
+		"int16":           "Int16",
+		"int32":           "Int32",
+		"int64":           "Int64",
+		"uint":            "UInt",
+		"uint8":           "UInt8",
+		"uint16":          "UInt16",
+		"uint32":          "UInt32",
+		"uint64":          "UInt64",
+		"float32":         "Float",
+		"float64":         "Double",
+		"bool":            "Bool",
+		"interface{}":     "JSONValue",
+		"interface {}":    "JSONValue",
+		"any":             "JSONValue",
+		"nil":             "JSONValue",
+		"time.Time":       "String",
+		"time.Duration":   "Int64",
+		"json.RawMessage": "JSONValue",
+	},
+	array: func(elem string) string { return "[" + elem + "]" },
+	dict:  func(key, value string) string { return "[" + key + ": " + value + "]" },
+	named: func(name string) string { return lo.PascalCase(name) },
+}
+
+var swiftKeywords = []string{
+	"associatedtype", "class", "deinit", "enum", "extension", "func", "import", "init", "inout",
+	"internal", "let", "operator", "private", "protocol", "public", "static", "struct", "subscript",
+	"typealias", "var", "break", "case", "continue", "default", "defer", "do", "else", "fallthrough",
+	"for", "guard", "if", "in", "repeat", "return", "switch", "where", "while", "as", "catch", "false",
+	"is", "nil", "rethrows", "super", "self", "throw", "throws", "true", "try",
+}
+
+func swiftIdentifier(alias string) string {
+	name := lo.CamelCase(alias)
+	if lo.Contains(swiftKeywords, name) {
+		return "`" + name + "`"
+	}
+
+	return name
+}
+
+// swiftStruct declares the struct, wrapping the fields in recursive with
+// Indirect, as a struct cannot store its own type inline.
+func swiftStruct(sb *strings.Builder, descriptor xrpc.TypeDescriptor, recursive map[string]bool) {
+	name := swiftSyntax.named(descriptor.TypeName)
+
+	sb.WriteString(fmt.Sprintf("public struct %s: Codable, Sendable {\n", name))
+	for _, field := range descriptor.Fields {
+		sb.WriteString(fmt.Sprintf(
+			"    %spublic var %s: %s%s\n",
+			lo.Ternary(recursive[descriptor.TypeName+"."+field.Name], "@Indirect ", ""),
+			swiftIdentifier(field.Alias), swiftSyntax.convert(stringFieldType(field)), lo.Ternary(field.Nillable, "?", ""),
+		))
+	}
+
+	if len(descriptor.Fields) > 0 {
+		sb.WriteString("\n    enum CodingKeys: String, CodingKey {\n")
+		for _, field := range descriptor.Fields {
+			sb.WriteString(fmt.Sprintf("        case %s = %s\n", swiftIdentifier(field.Alias), strconv.Quote(field.Alias)))
+		}
+		sb.WriteString("    }\n")
+	}
+
+	params := lo.Map(descriptor.Fields, func(field xrpc.FieldDescriptor, _ int) string {
+		return fmt.Sprintf(
+			"%s: %s%s", swiftIdentifier(field.Alias), swiftSyntax.convert(stringFieldType(field)), lo.Ternary(field.Nillable, "? = nil", ""),
+		)
+	})
+	sb.WriteString(fmt.Sprintf("\n    public init(%s) {\n", strings.Join(params, ", ")))
+	for _, field := range descriptor.Fields {
+		sb.WriteString(fmt.Sprintf("        self.%s = %s\n", strings.Trim(swiftIdentifier(field.Alias), "`"), swiftIdentifier(field.Alias)))
+	}
+	sb.WriteString("    }\n")
+
+	// Go marshals nil slices and maps as null, so those decode as empty.
+	sb.WriteString("\n    public init(from decoder: Decoder) throws {\n")
+	if len(descriptor.Fields) > 0 {
+		sb.WriteString("        let container = try decoder.container(keyedBy: CodingKeys.self)\n")
+	}
+	for _, field := range descriptor.Fields {
+		t := parseGoType(stringFieldType(field))
+		typ := swiftSyntax.spell(t)
+		key := strings.Trim(swiftIdentifier(field.Alias), "`")
+
+		switch {
+		case field.Nillable:
+			sb.WriteString(fmt.Sprintf("        self.%s = try container.decodeIfPresent(%s.self, forKey: .%s)\n", key, typ, key))
+		case t.kind == goTypeArray:
+			sb.WriteString(fmt.Sprintf("        self.%s = try container.decodeIfPresent(%s.self, forKey: .%s) ?? []\n", key, typ, key))
+		case t.kind == goTypeMap:
+			sb.WriteString(fmt.Sprintf("        self.%s = try container.decodeIfPresent(%s.self, forKey: .%s) ?? [:]\n", key, typ, key))
+		default:
+			sb.WriteString(fmt.Sprintf("        self.%s = try container.decode(%s.self, forKey: .%s)\n", key, typ, key))
+		}
+	}
+	sb.WriteString("    }\n}\n\n")
+}
+
+// swiftIndirect is only emitted for specs with recursive structs.
+const swiftIndirect = `/// Stores a value out of line, so a struct can hold its own type, e.g. an
+/// optional parent of the same type.
+@propertyWrapper
+public struct Indirect<Value: Codable & Sendable>: Codable, Sendable {
+    private final class Box: Sendable {
+        let value: Value
+
+        init(_ value: Value) {
+            self.value = value
+        }
+    }
+
+    private var box: Box
+
+    public init(wrappedValue: Value) {
+        box = Box(wrappedValue)
+    }
+
+    public var wrappedValue: Value {
+        get { box.value }
+        set { box = Box(newValue) }
+    }
+
+    public init(from decoder: Decoder) throws {
+        box = Box(try Value(from: decoder))
+    }
+
+    public func encode(to encoder: Encoder) throws {
+        try box.value.encode(to: encoder)
+    }
+}
+
+`
+
+const swiftClientRuntime = `/// An arbitrary JSON value, used for untyped fields and error details.
+public enum JSONValue: Codable, Sendable, Equatable {
+    case null
+    case bool(Bool)
+    case number(Double)
+    case string(String)
+    case array([JSONValue])
+    case object([String: JSONValue])
+
+    public init(from decoder: Decoder) throws {
+        let container = try decoder.singleValueContainer()
+        if container.decodeNil() {
+            self = .null
+        } else if let value = try? container.decode(Bool.self) {
+            self = .bool(value)
+        } else if let value = try? container.decode(Double.self) {
+            self = .number(value)
+        } else if let value = try? container.decode(String.self) {
+            self = .string(value)
+        } else if let value = try? container.decode([JSONValue].self) {
+            self = .array(value)
+        } else {
+            self = .object(try container.decode([String: JSONValue].self))
+        }
+    }
+
+    public func encode(to encoder: Encoder) throws {
+        var container = encoder.singleValueContainer()
+        switch self {
+        case .null: try container.encodeNil()
+        case .bool(let value): try container.encode(value)
+        case .number(let value): try container.encode(value)
+        case .string(let value): try container.encode(value)
+        case .array(let value): try container.encode(value)
+        case .object(let value): try container.encode(value)
+        }
+    }
+}
+
+/// A non-2xx response, with the error body's detail decoded when it was JSON.
+public struct XRPCError: Error, Sendable {
+    public let statusCode: Int
+    public let detail: JSONValue?
+    /// The id the call was sent with, as echoed by the server.
+    public let requestID: String?
+
+    /// Field-level validation messages, when the server rejected the input.
+    public var fieldErrors: [String: JSONValue]? {
+        if case .object(let fields) = detail { return fields }
+        return nil
+    }
+}
+
+private struct ErrorEnvelope: Decodable {
+    let detail: JSONValue?
+    let request_id: String?
+}
+
+/// Whether a call failing with status may succeed when retried.
+private func isRetryable(_ status: Int, _ idempotent: Bool) -> Bool {
+    [408, 429, 500, 502, 503, 504].contains(status) || (idempotent && status == 409)
+}
+
+/// Nanoseconds to wait before retrying, any Retry-After or an exponential backoff.
+private func retryDelay(_ attempt: Int, _ retryAfter: String?) -> UInt64 {
+    if let seconds = retryAfter.flatMap({ UInt64($0) }), seconds > 0 { return seconds * 1_000_000_000 }
+    let milliseconds = min(250 * pow(2, Double(attempt)), 10_000) * Double.random(in: 0.5...1)
+    return UInt64(milliseconds * 1_000_000)
+}
+
+private func queryItems(_ key: String, _ value: JSONValue) -> [URLQueryItem] {
+    switch value {
+    case .null: return []
+    case .bool(let value): return [URLQueryItem(name: key, value: value ? "true" : "false")]
+    case .number(let value):
+        let text = value.rounded() == value && abs(value) < 1e15 ? String(Int64(value)) : String(value)
+        return [URLQueryItem(name: key, value: text)]
+    case .string(let value): return [URLQueryItem(name: key, value: value)]
+    case .array(let values): return values.flatMap { queryItems(key, $0) }
+    case .object(let fields): return fields.flatMap { queryItems("\(key)[\($0.key)]", $0.value) }
+    }
+}
+
+`
+
+func GenerateSwiftClient(cfg SwiftClientConfig) error {
+	err := xrpc.WriteFile(cfg.Output, renderSwiftClient(cfg))
+	if err != nil {
+		return err
+	}
+
+	if cfg.PostHook != nil {
+		cfg.PostHook()
+	}
+
+	return nil
+}
+
+func renderSwiftClient(cfg SwiftClientConfig) string {
+	var sb strings.Builder
+	name := clientName(cfg.Spec)
+
+	sb.WriteString(fmt.Sprintf("// Generated xRPC client for %s.\n\nimport Foundation\n", cfg.Spec.Name))
+	sb.WriteString("#if canImport(FoundationNetworking)\nimport FoundationNetworking\n#endif\n\n")
+
+	structs := collectStructs(cfg.Spec)
+	recursive := recursiveFields(structs)
+	if len(recursive) > 0 {
+		sb.WriteString(swiftIndirect)
+	}
+	for _, descriptor := range structs {
+		swiftStruct(&sb, descriptor, recursive)
+	}
+
+	sb.WriteString(swiftClientRuntime)
+	sb.WriteString(commentLines("/// ", serverNotes(cfg.Spec.Server)))
+
+	sb.WriteString(fmt.Sprintf(`public final class %s {
+    public var baseURL: String
+    public var headers: [String: String]
+    /// Queries and idempotent mutations are retried this many times after network errors and 408, 429
+    /// or 5xx responses. Retried mutations reuse their Idempotency-Key.
+    public var retries: Int
+    private let session: URLSession
+    private let encoder = JSONEncoder()
+    private let decoder = JSONDecoder()
+
+    public init(baseURL: String = %s, headers: [String: String] = [:], retries: Int = 0, session: URLSession = .shared) {
+        self.baseURL = baseURL
+        self.headers = headers
+        self.retries = retries
+        self.session = session
+    }
+
+    private func request<Input: Encodable, Output: Decodable>(
+        _ method: String, _ path: String, _ input: Input, idempotent: Bool = false
+    ) async throws -> Output {
+        var base = baseURL
+        while base.hasSuffix("/") { base.removeLast() }
+        guard var components = URLComponents(string: base + path) else { throw URLError(.badURL) }
+
+        let body = try encoder.encode(input)
+        if method == "GET", case .object(let fields) = try decoder.decode(JSONValue.self, from: body) {
+            let items = fields.sorted { $0.key < $1.key }.flatMap { queryItems($0.key, $0.value) }
+            components.queryItems = items.isEmpty ? nil : items
+        }
+        guard let url = components.url else { throw URLError(.badURL) }
+
+        var request = URLRequest(url: url)
+        request.httpMethod = method
+        request.setValue("application/json", forHTTPHeaderField: "Accept")
+        request.setValue(UUID().uuidString, forHTTPHeaderField: "X-Request-ID")
+        if method != "GET" {
+            request.setValue("application/json", forHTTPHeaderField: "Content-Type")
+            request.httpBody = body
+        }
+        for (key, value) in headers {
+            request.setValue(value, forHTTPHeaderField: key)
+        }
+        if idempotent {
+            request.setValue(UUID().uuidString, forHTTPHeaderField: "Idempotency-Key")
+        }
+
+        let maxRetries = method == "GET" || idempotent ? retries : 0
+        var attempt = 0
+        while true {
+            let result: (Data, URLResponse)
+            do {
+                result = try await session.data(for: request)
+            } catch let error as URLError {
+                if attempt >= maxRetries { throw error }
+                try await Task.sleep(nanoseconds: retryDelay(attempt, nil))
+                attempt += 1
+                continue
+            }
+
+            let (data, response) = result
+            let httpResponse = response as? HTTPURLResponse
+            let statusCode = httpResponse?.statusCode ?? 0
+            if attempt < maxRetries && isRetryable(statusCode, idempotent) {
+                try await Task.sleep(nanoseconds: retryDelay(attempt, httpResponse?.value(forHTTPHeaderField: "Retry-After")))
+                attempt += 1
+                continue
+            }
+            if statusCode > 399 {
+                let envelope = try? decoder.decode(ErrorEnvelope.self, from: data)
+                let requestID = envelope?.request_id ?? httpResponse?.value(forHTTPHeaderField: "X-Request-ID")
+                throw XRPCError(statusCode: statusCode, detail: envelope?.detail, requestID: requestID)
+            }
+
+            return try decoder.decode(Output.self, from: data)
+        }
+    }
+`, name, strconv.Quote(cfg.Spec.ServerUrl)))
+
+	for _, procedure := range cfg.Spec.Procedures {
+		method := lo.Ternary(procedure.Type == xrpc.XRPCSpecProcedureTypeQuery, "GET", "POST")
+
+		sb.WriteString(fmt.Sprintf(
+			"\n    public func %s(_ input: %s) async throws -> %s {\n        try await request(%q, %s, input%s)\n    }\n",
+			lo.CamelCase(procedure.Path),
+			swiftSyntax.descriptor(procedure.Input),
+			swiftSyntax.descriptor(procedure.Output),
+			method,
+			strconv.Quote(procedure.Path),
+			lo.Ternary(procedure.Idempotent, ", idempotent: true", ""),
+		))
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
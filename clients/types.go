@@ -0,0 +1,273 @@
+package clients
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc"
+)
+
+type goTypeKind int
+
+const (
+	goTypePrimitive goTypeKind = iota
+	goTypeArray
+	goTypeMap
+	goTypeNamed
+)
+
+// goType is a parsed reflect type string, with pointers dropped since
+// nillability is tracked separately on descriptors.
+type goType struct {
+	kind goTypeKind
+	name string  // primitive or unqualified struct name
+	key  *goType // map key
+	elem *goType // array element or map value
+}
+
+func parseGoType(typeName string) *goType {
+	typeName = strings.TrimLeft(typeName, "*")
+
+	switch {
+	case typeName == "[]uint8":
+		// encoding/json marshals []byte as a base64 string
+		return &goType{kind: goTypePrimitive, name: "string"}
+	case strings.HasPrefix(typeName, "[]"):
+		return &goType{kind: goTypeArray, elem: parseGoType(typeName[2:])}
+	case strings.HasPrefix(typeName, "["):
+		return &goType{kind: goTypeArray, elem: parseGoType(typeName[strings.Index(typeName, "]")+1:])}
+	case strings.HasPrefix(typeName, "map["):
+		key, value := splitMapType(typeName)
+		return &goType{kind: goTypeMap, key: parseGoType(key), elem: parseGoType(value)}
+	}
+
+	if _, exists := goToTSType[typeName]; exists {
+		return &goType{kind: goTypePrimitive, name: typeName}
+	}
+
+	return &goType{kind: goTypeNamed, name: typeName[strings.LastIndex(typeName, ".")+1:]}
+}
+
+// descriptorGoType parses a type descriptor, which may be an array.
+func descriptorGoType(descriptor xrpc.TypeDescriptor) *goType {
+	if descriptor.Array != nil {
+		return &goType{kind: goTypeArray, elem: descriptorGoType(*descriptor.Array)}
+	}
+
+	return parseGoType(descriptor.TypeName)
+}
+
+// typeSyntax describes how a target language spells the Go types recorded in
+// field descriptors.
+type typeSyntax struct {
+	primitives map[string]string
+	array      func(elem string) string
+	dict       func(key, value string) string
+	named      func(name string) string
+}
+
+func (s typeSyntax) spell(t *goType) string {
+	switch t.kind {
+	case goTypeArray:
+		return s.array(s.spell(t.elem))
+	case goTypeMap:
+		return s.dict(s.spell(t.key), s.spell(t.elem))
+	case goTypeNamed:
+		return s.named(t.name)
+	}
+
+	return s.primitives[t.name]
+}
+
+// convert maps a reflect type string such as "[]*main.Post" or
+// "map[string]int" to the target language.
+func (s typeSyntax) convert(typeName string) string {
+	return s.spell(parseGoType(typeName))
+}
+
+// descriptor maps a type descriptor, which may be an array, to the target
+// language.
+func (s typeSyntax) descriptor(descriptor xrpc.TypeDescriptor) string {
+	return s.spell(descriptorGoType(descriptor))
+}
+
+// isPrimitive reports whether the descriptor names a builtin Go type rather
+// than a struct that needs a declaration.
+func isPrimitive(descriptor xrpc.TypeDescriptor) bool {
+	_, exists := goToTSType[descriptor.TypeName]
+	return exists || descriptor.TypeName == ""
+}
+
+// collectStructs returns every struct referenced by the spec's procedures,
+// nested ones included, each once and ordered so dependencies come first.
+func collectStructs(spec xrpc.TRPCSpec) []xrpc.TypeDescriptor {
+	structs := []xrpc.TypeDescriptor{}
+	seen := map[string]bool{}
+
+	var visit func(descriptor xrpc.TypeDescriptor)
+	visit = func(descriptor xrpc.TypeDescriptor) {
+		if descriptor.Array != nil {
+			visit(*descriptor.Array)
+			return
+		}
+
+		if isPrimitive(descriptor) || seen[descriptor.TypeName] {
+			return
+		}
+		seen[descriptor.TypeName] = true
+
+		for _, field := range descriptor.Fields {
+			if field.Struct != nil {
+				visit(*field.Struct)
+			}
+		}
+
+		structs = append(structs, descriptor)
+	}
+
+	for _, procedure := range spec.Procedures {
+		visit(procedure.Input)
+		visit(procedure.Output)
+	}
+
+	return structs
+}
+
+// recursiveFields returns the fields, as "Struct.Field", that hold a struct
+// which contains the struct declaring them again, e.g. Manager *User in User.
+// Slices and maps are left out, as they hold their elements out of line in
+// every target language.
+func recursiveFields(structs []xrpc.TypeDescriptor) map[string]bool {
+	contains := map[string][]string{}
+	for _, descriptor := range structs {
+		for _, field := range descriptor.Fields {
+			if t := parseGoType(stringFieldType(field)); t.kind == goTypeNamed {
+				contains[descriptor.TypeName] = append(contains[descriptor.TypeName], t.name)
+			}
+		}
+	}
+
+	reaches := func(from string, to string) bool {
+		visited := map[string]bool{}
+		queue := []string{from}
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			if name == to {
+				return true
+			}
+			if !visited[name] {
+				visited[name] = true
+				queue = append(queue, contains[name]...)
+			}
+		}
+		return false
+	}
+
+	fields := map[string]bool{}
+	for _, descriptor := range structs {
+		for _, field := range descriptor.Fields {
+			if t := parseGoType(stringFieldType(field)); t.kind == goTypeNamed && reaches(t.name, descriptor.TypeName) {
+				fields[descriptor.TypeName+"."+field.Name] = true
+			}
+		}
+	}
+
+	return fields
+}
+
+// clientName derives the generated client type name from the spec name,
+// e.g. "Post Service" becomes "PostServiceClient".
+func clientName(spec xrpc.TRPCSpec) string {
+	return lo.PascalCase(spec.Name) + "Client"
+}
+
+// deprecationNotice explains a deprecation, e.g. "Use /post/search/ instead.
+// Removed after 2025-01-01."
+func deprecationNotice(deprecation *xrpc.XRPCSpecDeprecation) string {
+	notice := lo.Ternary(deprecation.Reason != "", deprecation.Reason, "This procedure is deprecated.")
+	if deprecation.Sunset != nil {
+		notice = strings.TrimRight(notice, ".") + ". Removed after " + deprecation.Sunset.Format("2006-01-02") + "."
+	}
+
+	return notice
+}
+
+// serverNotes describes the server settings that affect callers, for the docs
+// of generated clients.
+func serverNotes(server *xrpc.XRPCSpecServer) []string {
+	if server == nil {
+		return nil
+	}
+
+	notes := []string{}
+	if server.MaxBodySize > 0 {
+		notes = append(notes, fmt.Sprintf("Request bodies over %s are rejected with a 413.", formatBytes(server.MaxBodySize)))
+	}
+	if server.Timeout > 0 {
+		notes = append(notes, fmt.Sprintf("Requests taking over %ds fail with a 503.", server.Timeout))
+	}
+	if server.Gzip {
+		notes = append(notes, "Responses are gzip-compressed for clients sending Accept-Encoding: gzip.")
+	}
+	if len(server.CORSOrigins) > 0 {
+		origins := lo.Ternary(lo.Contains(server.CORSOrigins, "*"), "any origin", strings.Join(server.CORSOrigins, ", "))
+		notes = append(notes, "Browsers may call the server from "+origins+".")
+	}
+
+	return notes
+}
+
+func formatBytes(size int64) string {
+	switch {
+	case size%(1<<20) == 0:
+		return fmt.Sprintf("%d MiB", size>>20)
+	case size%(1<<10) == 0:
+		return fmt.Sprintf("%d KiB", size>>10)
+	}
+
+	return fmt.Sprintf("%d bytes", size)
+}
+
+// commentLines prefixes each line, e.g. with "/// " for doc comments.
+func commentLines(prefix string, lines []string) string {
+	return strings.Join(lo.Map(lines, func(line string, _ int) string { return prefix + line + "\n" }), "")
+}
+
+// fieldType returns the field's reflect type string with its enum named in
+// place of the underlying string, e.g. "[]PostStatus" for a []string field
+// restricted by an enum tag.
+func fieldType(field xrpc.FieldDescriptor) string {
+	if field.Enum == nil {
+		return lo.CoalesceOrEmpty(field.Underlying, field.Type)
+	}
+
+	return field.Type[:strings.LastIndexAny(field.Type, "]*")+1] + field.Enum.TypeName
+}
+
+// stringFieldType returns the field's reflect type string with any enum
+// spelled as string, for targets that don't declare enum types.
+func stringFieldType(field xrpc.FieldDescriptor) string {
+	if field.Enum == nil {
+		return lo.CoalesceOrEmpty(field.Underlying, field.Type)
+	}
+
+	return field.Type[:strings.LastIndexAny(field.Type, "]*")+1] + "string"
+}
+
+// collectEnums returns every enum used by the spec's structs, each once.
+func collectEnums(spec xrpc.TRPCSpec) []xrpc.EnumDescriptor {
+	enums := []xrpc.EnumDescriptor{}
+	for _, descriptor := range collectStructs(spec) {
+		for _, field := range descriptor.Fields {
+			if field.Enum != nil && !lo.ContainsBy(enums, func(enum xrpc.EnumDescriptor) bool {
+				return enum.TypeName == field.Enum.TypeName
+			}) {
+				enums = append(enums, *field.Enum)
+			}
+		}
+	}
+
+	return enums
+}
@@ -0,0 +1,234 @@
+package clients
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc"
+	"github.com/struckchure/xrpc/internals"
+)
+
+var goToTSType = map[string]string{
+	"string":          "string",
+	"int":             "number",
+	"int8":            "number",
+	"int16":           "number",
+	"int32":           "number",
+	"int64":           "number",
+	"uint":            "number",
+	"uint8":           "number",
+	"uint16":          "number",
+	"uint32":          "number",
+	"uint64":          "number",
+	"float32":         "number",
+	"float64":         "number",
+	"bool":            "boolean",
+	"interface{}":     "any",
+	"interface {}":    "any",
+	"any":             "any",
+	"nil":             "null",
+	"time.Time":       "string",
+	"time.Duration":   "number",
+	"json.RawMessage": "unknown",
+}
+
+// Convert a Go type to TypeScript type, handling arrays and maps
+func convertGoTypeToTS(goType xrpc.TypeDescriptor) string {
+	if goType.Array != nil {
+		return internals.TSArray(convertGoTypeToTS(*goType.Array))
+	}
+	return convertGoTypeStringToTS(goType.TypeName)
+}
+
+// convertGoTypeStringToTS maps a reflect type string such as "[]*main.Post"
+// or "map[string]int" to its TypeScript equivalent.
+func convertGoTypeStringToTS(goType string) string {
+	goType = strings.TrimLeft(goType, "*")
+
+	switch {
+	case goType == "[]uint8":
+		return "string" // encoding/json marshals []byte as base64
+	case strings.HasPrefix(goType, "[]"):
+		return internals.TSArray(convertGoTypeStringToTS(goType[2:]))
+	case strings.HasPrefix(goType, "["):
+		return internals.TSArray(convertGoTypeStringToTS(goType[strings.Index(goType, "]")+1:]))
+	case strings.HasPrefix(goType, "map["):
+		key, value := splitMapType(goType)
+		return internals.TSGeneric("Record", convertGoTypeStringToTS(key), convertGoTypeStringToTS(value))
+	}
+
+	if tsType, exists := goToTSType[goType]; exists {
+		return tsType
+	}
+
+	// Fallback to using the struct name as the TypeScript type
+	return lo.PascalCase(goType[strings.LastIndex(goType, ".")+1:])
+}
+
+// splitMapType splits "map[K]V" into K and V, respecting nested brackets.
+func splitMapType(goType string) (string, string) {
+	depth := 0
+	for i := len("map"); i < len(goType); i++ {
+		switch goType[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return goType[len("map["):i], goType[i+1:]
+			}
+		}
+	}
+
+	return "string", "interface{}"
+}
+
+type TypeScriptClientConfig struct {
+	Spec     xrpc.TRPCSpec
+	Output   string
+	PostHook func()
+	// Formatter post-processes the generated source before it is written,
+	// see CommandFormatter.
+	Formatter func(string) (string, error)
+}
+
+// CommandFormatter returns a formatter that pipes the source through an
+// external command, e.g. CommandFormatter("npx", "prettier", "--parser", "typescript").
+func CommandFormatter(name string, args ...string) func(string) (string, error) {
+	return func(src string) (string, error) {
+		var stdout, stderr bytes.Buffer
+
+		cmd := exec.Command(name, args...)
+		cmd.Stdin = strings.NewReader(src)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("failed to format with %s: %w: %s", name, err, stderr.String())
+		}
+
+		return stdout.String(), nil
+	}
+}
+
+// writeTSFile formats and writes the generated file, then runs the post hook.
+func writeTSFile(cfg TypeScriptClientConfig, file *internals.TSFile) error {
+	file.Formatter = cfg.Formatter
+
+	out, err := file.Format()
+	if err != nil {
+		return err
+	}
+
+	err = xrpc.WriteFile(cfg.Output, out)
+	if err != nil {
+		return err
+	}
+
+	if cfg.PostHook != nil {
+		cfg.PostHook()
+	}
+
+	return nil
+}
+
+// tsInterfaceFields converts field descriptors in declaration order, declaring
+// any nested struct types they reference.
+func tsInterfaceFields(file *internals.TSFile, fields []xrpc.FieldDescriptor, types map[string]bool) []internals.TSField {
+	return lo.Map(fields, func(field xrpc.FieldDescriptor, _ int) internals.TSField {
+		if field.Struct != nil {
+			declareTSType(file, *field.Struct, types)
+		}
+		if field.Enum != nil {
+			declareTSEnum(file, *field.Enum, types)
+		}
+
+		return internals.TSField{
+			Name:     field.Alias,
+			Type:     tsFieldType(field),
+			Optional: field.OmitEmpty,
+			Doc:      tsFieldDoc(field),
+		}
+	})
+}
+
+// tsFieldType adds null to the types encoding/json may write as null: nil
+// pointers, and nil slices and maps unless omitempty leaves them out.
+func tsFieldType(field xrpc.FieldDescriptor) string {
+	typ := convertGoTypeStringToTS(fieldType(field))
+	if typ == "any" || typ == "unknown" {
+		return typ
+	}
+
+	goType := fieldType(field)
+	collection := strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map[")
+	if field.Nillable || (collection && !field.OmitEmpty) {
+		return internals.TSUnion(typ, "null")
+	}
+
+	return typ
+}
+
+// tsFieldDoc documents a field with the doc, format and example struct tags.
+func tsFieldDoc(field xrpc.FieldDescriptor) string {
+	lines := []string{}
+	if field.Description != "" {
+		lines = append(lines, field.Description)
+	}
+	if field.Format != "" {
+		lines = append(lines, "@format "+field.Format)
+	}
+	if field.Example != "" {
+		lines = append(lines, "@example "+field.Example)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// declareTSEnum declares the enum as a union of string literals, once.
+func declareTSEnum(file *internals.TSFile, enum xrpc.EnumDescriptor, types map[string]bool) {
+	typeName := lo.PascalCase(enum.TypeName)
+	if types[typeName] {
+		return
+	}
+	types[typeName] = true
+
+	file.AddNode(&internals.TSTypeAlias{
+		Name:   typeName,
+		Type:   internals.TSUnion(lo.Map(enum.Values, func(value string, _ int) string { return fmt.Sprintf("%q", value) })...),
+		Export: true,
+	})
+}
+
+// declareTSType adds an interface for the descriptor to the file unless it is
+// a primitive or was already declared, and returns the TypeScript type name.
+func declareTSType(file *internals.TSFile, descriptor xrpc.TypeDescriptor, types map[string]bool) string {
+	if descriptor.Array != nil {
+		return internals.TSArray(declareTSType(file, *descriptor.Array, types))
+	}
+
+	if tsType, exists := goToTSType[descriptor.TypeName]; exists {
+		return tsType
+	}
+
+	typeName := lo.PascalCase(descriptor.TypeName)
+	if _, exists := types[typeName]; !exists && typeName != "" {
+		types[typeName] = true
+
+		// Fields are converted first so nested types are declared before use.
+		iface := &internals.TSInterface{Name: typeName, Export: true}
+		iface.Fields = tsInterfaceFields(file, descriptor.Fields, types)
+		file.AddNode(iface)
+	}
+
+	return typeName
+}
+
+// addTSTypes declares the input and output types of a procedure and returns
+// the TypeScript type names used to reference them.
+func addTSTypes(file *internals.TSFile, procedure xrpc.XRPCSpecProcedure, types map[string]bool) (string, string) {
+	return declareTSType(file, procedure.Input, types), declareTSType(file, procedure.Output, types)
+}
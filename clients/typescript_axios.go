@@ -0,0 +1,127 @@
+package clients
+
+import (
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc"
+	"github.com/struckchure/xrpc/internals"
+)
+
+func GenerateTypeScriptAxiosClient(cfg TypeScriptClientConfig) error {
+	file := &internals.TSFile{}
+
+	file.AddNode(&internals.TSImport{
+		Module:  "axios",
+		Default: "axios",
+		Names:   []string{"type AxiosInstance", "type AxiosRequestConfig"},
+	})
+
+	types := map[string]bool{}
+	procedures := lo.Map(cfg.Spec.Procedures, func(procedure xrpc.XRPCSpecProcedure, _ int) tsProcedure {
+		inputTypeName, outputTypeName := addTSTypes(file, procedure, types)
+
+		return tsProcedure{XRPCSpecProcedure: procedure, InputType: inputTypeName, OutputType: outputTypeName}
+	})
+
+	addTSErrors(file)
+
+	for _, function := range tsRetryFunctions {
+		file.AddNode(function)
+	}
+
+	// A shared instance so consumers can register interceptors once, e.g.
+	// client.interceptors.request.use(...). Arrays are sent as repeated keys,
+	// requests get an X-Request-ID, and error responses are rejected as
+	// XRPCClientError. Trace headers are left to an interceptor. Procedures
+	// are sent through request, which retries them per clientOptions.
+	notes := append(serverNotes(cfg.Spec.Server),
+		"No trace headers are sent unless an interceptor adds them, e.g. with OpenTelemetry:",
+		"`client.interceptors.request.use((config) => { propagation.inject(context.active(), config.headers); return config; });`",
+	)
+	lines := append(append([]string{"/**"}, lo.Map(notes, func(note string, _ int) string { return " * " + note })...), " */")
+	file.AddNode(&internals.TSRaw{Lines: append(lines,
+		"export const client: AxiosInstance = axios.create({",
+		fmt.Sprintf("  baseURL: %q,", cfg.Spec.ServerUrl),
+		"  paramsSerializer: { indexes: null },",
+		"});",
+		"",
+		"client.interceptors.request.use((config) => {",
+		"  if (!config.headers.has(\"X-Request-ID\")) config.headers.set(\"X-Request-ID\", crypto.randomUUID());",
+		"  return config;",
+		"});",
+		"",
+		"client.interceptors.response.use(undefined, (error: unknown) =>",
+		"  Promise.reject(",
+		"    axios.isAxiosError(error) && error.response",
+		"      ? new XRPCClientError(error.response.status, error.response.data, error.response.headers[\"x-request-id\"] as string | undefined)",
+		"      : error,",
+		"  ),",
+		");",
+		"",
+		"export const clientOptions = {",
+		"  /**",
+		"   * Retries queries and idempotent mutations this many times after network errors and 408, 429 or 5xx",
+		"   * responses, waiting for any Retry-After. Retried mutations reuse their Idempotency-Key.",
+		"   */",
+		"  retries: 0,",
+		"};",
+		"",
+		"async function request<T>(config: AxiosRequestConfig, idempotent = false): Promise<T> {",
+		"  const retries = config.method === \"GET\" || idempotent ? clientOptions.retries : 0;",
+		"  for (let attempt = 0; ; attempt++) {",
+		"    const response = await client.request<T>({ ...config, validateStatus: () => true }).catch((error: unknown) => {",
+		"      if (attempt >= retries || axios.isCancel(error)) throw error;",
+		"      return undefined;",
+		"    });",
+		"    if (response && (attempt >= retries || !isRetryable(response.status, idempotent))) {",
+		"      if (response.status < 200 || response.status > 299) {",
+		"        throw new XRPCClientError(response.status, response.data, response.headers[\"x-request-id\"] as string | undefined);",
+		"      }",
+		"      return response.data;",
+		"    }",
+		"    await new Promise((resolve) => setTimeout(resolve, retryDelay(attempt, response?.headers[\"retry-after\"] as string | undefined)));",
+		"  }",
+		"}",
+	)})
+
+	for _, procedure := range procedures {
+		inputTypeName, outputTypeName := procedure.InputType, procedure.OutputType
+
+		var body []string
+		if procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
+			body = []string{
+				fmt.Sprintf("return request<%s>({ ...config, method: \"GET\", url: %q, params: data });", outputTypeName, procedure.Path),
+			}
+		} else if procedure.Idempotent {
+			// Retries re-send the config, so they reuse the key.
+			body = []string{
+				fmt.Sprintf("return request<%s>(", outputTypeName),
+				fmt.Sprintf("  { ...config, method: \"POST\", url: %q, data, headers: { \"Idempotency-Key\": crypto.randomUUID(), ...config?.headers } },", procedure.Path),
+				"  true,",
+				");",
+			}
+		} else {
+			body = []string{
+				fmt.Sprintf("return request<%s>({ ...config, method: \"POST\", url: %q, data });", outputTypeName, procedure.Path),
+			}
+		}
+
+		file.AddNode(&internals.TSFunction{
+			Name:       lo.PascalCase(procedure.Path),
+			ReturnType: internals.TSGeneric("Promise", outputTypeName),
+			Params: []internals.TSParam{
+				{Name: "data", Type: inputTypeName},
+				{Name: "config", Type: "AxiosRequestConfig", Optional: true},
+			},
+			Body:   body,
+			Export: true,
+			Async:  true,
+			Doc: tsProcedureDoc(
+				procedure.XRPCSpecProcedure, lo.PascalCase(procedure.Path), "Pass `config.signal` (an AbortSignal) to cancel the request.",
+			),
+		})
+	}
+
+	return writeTSFile(cfg, file)
+}
@@ -0,0 +1,329 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc"
+	"github.com/struckchure/xrpc/internals"
+)
+
+type tsTransport string
+
+const (
+	tsTransportFetch tsTransport = "fetch"
+	tsTransportKy    tsTransport = "ky"
+)
+
+// tsProcedure is a procedure exposed by the generated client, along with the
+// free function delegating to the default client.
+type tsProcedure struct {
+	xrpc.XRPCSpecProcedure
+	Function   string
+	InputType  string
+	OutputType string
+}
+
+// tsRouterNode mirrors the router structure, so "/post/list/" becomes
+// client.post.list.
+type tsRouterNode struct {
+	name     string
+	leaf     string
+	doc      string
+	children []*tsRouterNode
+}
+
+func (n *tsRouterNode) child(name string) *tsRouterNode {
+	node, exists := lo.Find(n.children, func(c *tsRouterNode) bool { return c.name == name })
+	if !exists {
+		node = &tsRouterNode{name: name}
+		n.children = append(n.children, node)
+	}
+
+	return node
+}
+
+func (n *tsRouterNode) render(indent string) []string {
+	lines := []string{}
+	for _, c := range n.children {
+		key := internals.TSPropertyName(c.name)
+		if c.doc != "" {
+			lines = append(lines, strings.Split(strings.TrimSuffix(internals.TSDoc(c.doc, indent), "\n"), "\n")...)
+		}
+
+		switch {
+		case len(c.children) == 0:
+			lines = append(lines, fmt.Sprintf("%s%s: %s,", indent, key, c.leaf))
+		case c.leaf == "":
+			lines = append(lines, fmt.Sprintf("%s%s: {", indent, key))
+			lines = append(lines, c.render(indent+"  ")...)
+			lines = append(lines, indent+"},")
+		default:
+			// A procedure that is also a router prefix stays callable.
+			lines = append(lines, fmt.Sprintf("%s%s: Object.assign(%s, {", indent, key, c.leaf))
+			lines = append(lines, c.render(indent+"  ")...)
+			lines = append(lines, indent+"}),")
+		}
+	}
+
+	return lines
+}
+
+func tsRouterSegments(path string) []string {
+	return lo.Map(lo.Compact(strings.Split(path, "/")), func(segment string, _ int) string {
+		return lo.CamelCase(segment)
+	})
+}
+
+func tsRouterAccess(segments []string) string {
+	return strings.Join(lo.Map(segments, func(segment string, _ int) string {
+		if internals.TSPropertyName(segment) == segment {
+			return "." + segment
+		}
+		return "[" + internals.TSPropertyName(segment) + "]"
+	}), "")
+}
+
+var tsSearchParamsFunction = &internals.TSFunction{
+	Name:       "toSearchParams",
+	Params:     []internals.TSParam{{Name: "data", Type: "unknown"}},
+	ReturnType: "URLSearchParams",
+	Doc:        "Encodes input as query parameters: arrays repeat the key and nested\nobjects use brackets, e.g. filter[author]=1.",
+	Body: []string{
+		"const params = new URLSearchParams();",
+		"const add = (key: string, value: unknown): void => {",
+		"  if (value === null || value === undefined) return;",
+		"  if (Array.isArray(value)) {",
+		"    value.forEach((item) => add(key, item));",
+		"  } else if (typeof value === \"object\") {",
+		"    Object.entries(value as Record<string, unknown>).forEach(([k, v]) => add(`${key}[${k}]`, v));",
+		"  } else {",
+		"    params.append(key, String(value));",
+		"  }",
+		"};",
+		"Object.entries((data ?? {}) as Record<string, unknown>).forEach(([key, value]) => add(key, value));",
+		"return params;",
+	},
+}
+
+// tsRetryFunctions decide whether and when requests are retried: on
+// responses that may succeed later, and for idempotent mutations on calls
+// still in flight, after any Retry-After or an exponential backoff.
+var tsRetryFunctions = []*internals.TSFunction{
+	{
+		Name:       "isRetryable",
+		Params:     []internals.TSParam{{Name: "status", Type: "number"}, {Name: "idempotent", Type: "boolean"}},
+		ReturnType: "boolean",
+		Doc:        "Whether a failed call may succeed when retried.",
+		Body:       []string{"return [408, 429, 500, 502, 503, 504].includes(status) || (idempotent && status === 409);"},
+	},
+	{
+		Name:       "retryDelay",
+		Params:     []internals.TSParam{{Name: "attempt", Type: "number"}, {Name: "retryAfter", Type: "string | null", Optional: true}},
+		ReturnType: "number",
+		Doc:        "Milliseconds to wait before retrying, any Retry-After or an exponential backoff.",
+		Body: []string{
+			"const seconds = Number(retryAfter);",
+			"if (seconds > 0) return seconds * 1000;",
+			"return Math.min(250 * 2 ** attempt, 10_000) * (0.5 + Math.random() / 2);",
+		},
+	},
+}
+
+// tsProcedureDoc documents a procedure with its description, any notes,
+// examples calling it through call, and deprecation.
+func tsProcedureDoc(procedure xrpc.XRPCSpecProcedure, call string, notes ...string) string {
+	lines := []string{}
+	for _, paragraph := range append([]string{procedure.Description}, notes...) {
+		if paragraph != "" {
+			lines = append(lines, lo.Ternary(len(lines) > 0, "\n", "")+paragraph)
+		}
+	}
+
+	for _, example := range procedure.Examples {
+		input, _ := json.Marshal(example.Input)
+		lines = append(lines, "@example", fmt.Sprintf("await %s(%s);", call, input))
+	}
+
+	if procedure.Deprecation != nil {
+		lines = append(lines, "@deprecated "+deprecationNotice(procedure.Deprecation))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func tsRequestBody(transport tsTransport) []string {
+	lines := []string{
+		"async function request<T>(method: \"GET\" | \"POST\", path: string, data: unknown, idempotent = false): Promise<T> {",
+		"  const headers: Record<string, string> = {",
+		"    ...(typeof options.headers === \"function\" ? await options.headers() : options.headers),",
+		"  };",
+		"  if (!headers[\"X-Request-ID\"]) headers[\"X-Request-ID\"] = crypto.randomUUID();",
+		"  if (idempotent && !headers[\"Idempotency-Key\"]) headers[\"Idempotency-Key\"] = crypto.randomUUID();",
+		"  options.propagate?.(headers);",
+		"  const retries = method === \"GET\" || idempotent ? (options.retries ?? 0) : 0;",
+		"",
+	}
+
+	if transport == tsTransportKy {
+		lines = append(lines,
+			"  const send = () =>",
+			"    ky(baseUrl + path, {",
+			"      method,",
+			"      headers,",
+			"      fetch: options.fetch,",
+			"      throwHttpErrors: false,",
+			"      retry: 0,",
+			"      ...(method === \"GET\" ? { searchParams: toSearchParams(data) } : { json: data }),",
+			"    });",
+		)
+	} else {
+		lines = append(lines,
+			"  let url = baseUrl + path;",
+			"  const init: RequestInit = { method, headers };",
+			"  if (method === \"GET\") {",
+			"    const query = toSearchParams(data).toString();",
+			"    if (query) url += `?${query}`;",
+			"  } else {",
+			"    headers[\"Content-Type\"] = \"application/json\";",
+			"    init.body = JSON.stringify(data);",
+			"  }",
+			"  const send = () => (options.fetch ?? fetch)(url, init);",
+		)
+	}
+
+	return append(lines,
+		"",
+		"  try {",
+		"    for (let attempt = 0; ; attempt++) {",
+		"      const response = await send().catch((error: unknown) => {",
+		"        if (attempt >= retries) throw error;",
+		"        return undefined;",
+		"      });",
+		"      if (response && (attempt >= retries || !isRetryable(response.status, idempotent))) {",
+		"        if (!response.ok) throw await XRPCClientError.fromResponse(response);",
+		"        return (await response.json()) as T;",
+		"      }",
+		"      await new Promise((resolve) => setTimeout(resolve, retryDelay(attempt, response?.headers.get(\"Retry-After\"))));",
+		"    }",
+		"  } catch (error) {",
+		"    options.onError?.(error);",
+		"    throw error;",
+		"  }",
+		"}",
+	)
+}
+
+// addTSClient declares the procedure types, an exported createClient factory
+// returning an object that mirrors the router, and a free function per
+// procedure that calls the default client, which configureClient replaces.
+func addTSClient(file *internals.TSFile, spec xrpc.TRPCSpec, transport tsTransport) []tsProcedure {
+	types := map[string]bool{}
+	procedures := lo.Map(spec.Procedures, func(procedure xrpc.XRPCSpecProcedure, _ int) tsProcedure {
+		inputTypeName, outputTypeName := addTSTypes(file, procedure, types)
+
+		return tsProcedure{
+			XRPCSpecProcedure: procedure,
+			Function:          lo.PascalCase(procedure.Path),
+			InputType:         inputTypeName,
+			OutputType:        outputTypeName,
+		}
+	})
+
+	addTSErrors(file)
+
+	file.AddNode(&internals.TSInterface{
+		Name:   "ClientOptions",
+		Export: true,
+		Fields: []internals.TSField{
+			{Name: "baseUrl", Type: "string", Optional: true, Doc: fmt.Sprintf("Defaults to %s.", spec.ServerUrl)},
+			{
+				Name:     "headers",
+				Type:     internals.TSUnion("Record<string, string>", "(() => Record<string, string> | Promise<Record<string, string>>)"),
+				Optional: true,
+				Doc:      "Sent with every request; pass a function to resolve them per request, e.g. auth tokens.",
+			},
+			{Name: "fetch", Type: "typeof fetch", Optional: true},
+			{
+				Name:     "propagate",
+				Type:     "(headers: Record<string, string>) => void",
+				Optional: true,
+				Doc:      "Injects the trace context into request headers, e.g.\n`(headers) => propagation.inject(context.active(), headers)` with OpenTelemetry.\nWithout it no trace headers are sent.",
+			},
+			{
+				Name:     "retries",
+				Type:     "number",
+				Optional: true,
+				Doc: "Retries queries and idempotent mutations this many times after network errors and 408, 429 or 5xx\n" +
+					"responses, waiting for any Retry-After. Retried mutations reuse their Idempotency-Key. Defaults to 0.",
+			},
+			{
+				Name:     "onError",
+				Type:     "(error: unknown) => void",
+				Optional: true,
+				Doc:      "Called with any error, including XRPCClientError for non-2xx responses, before it is rethrown.",
+			},
+		},
+	})
+
+	file.AddNode(tsSearchParamsFunction)
+	for _, function := range tsRetryFunctions {
+		file.AddNode(function)
+	}
+
+	router := &tsRouterNode{}
+	for _, procedure := range procedures {
+		node := router
+		for _, segment := range tsRouterSegments(procedure.Path) {
+			node = node.child(segment)
+		}
+
+		method := lo.Ternary(procedure.Type == xrpc.XRPCSpecProcedureTypeQuery, "GET", "POST")
+		node.doc = tsProcedureDoc(procedure.XRPCSpecProcedure, "client"+tsRouterAccess(tsRouterSegments(procedure.Path)))
+		node.leaf = fmt.Sprintf(
+			"(input: %s) => request<%s>(%q, %q, input%s)",
+			procedure.InputType, procedure.OutputType, method, procedure.Path, lo.Ternary(procedure.Idempotent, ", true", ""),
+		)
+	}
+
+	body := []string{fmt.Sprintf("const baseUrl = (options.baseUrl ?? %q).replace(/\\/+$/, \"\");", spec.ServerUrl), ""}
+	body = append(body, tsRequestBody(transport)...)
+	body = append(body, "", "return {")
+	body = append(body, router.render("  ")...)
+	body = append(body, "};")
+
+	file.AddNode(&internals.TSFunction{
+		Name:   "createClient",
+		Params: []internals.TSParam{{Name: "options", Type: "ClientOptions", Default: "{}"}},
+		Body:   body,
+		Export: true,
+		Doc:    strings.Join(serverNotes(spec.Server), "\n"),
+	})
+
+	file.AddNode(&internals.TSTypeAlias{Name: "Client", Type: "ReturnType<typeof createClient>", Export: true})
+
+	file.AddNode(&internals.TSRaw{Lines: []string{"let defaultClient: Client = createClient();"}})
+	file.AddNode(&internals.TSFunction{
+		Name:   "configureClient",
+		Params: []internals.TSParam{{Name: "options", Type: "ClientOptions"}},
+		Body:   []string{"defaultClient = createClient(options);"},
+		Export: true,
+		Doc:    "Replaces the client used by the exported procedure functions.",
+	})
+
+	for _, procedure := range procedures {
+		file.AddNode(&internals.TSFunction{
+			Name:       procedure.Function,
+			ReturnType: internals.TSGeneric("Promise", procedure.OutputType),
+			Params:     []internals.TSParam{{Name: "data", Type: procedure.InputType}},
+			Body:       []string{"return defaultClient" + tsRouterAccess(tsRouterSegments(procedure.Path)) + "(data);"},
+			Export:     true,
+			Async:      true,
+			Doc:        tsProcedureDoc(procedure.XRPCSpecProcedure, procedure.Function),
+		})
+	}
+
+	return procedures
+}
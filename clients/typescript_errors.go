@@ -0,0 +1,129 @@
+package clients
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc/internals"
+)
+
+// tsErrorCodes names the statuses the server is expected to return, e.g.
+// 400 becomes "BAD_REQUEST".
+var tsErrorCodes = []int{
+	http.StatusBadRequest,
+	http.StatusUnauthorized,
+	http.StatusForbidden,
+	http.StatusNotFound,
+	http.StatusMethodNotAllowed,
+	http.StatusRequestTimeout,
+	http.StatusConflict,
+	http.StatusPreconditionFailed,
+	http.StatusRequestEntityTooLarge,
+	http.StatusUnprocessableEntity,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusNotImplemented,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+func tsErrorCode(status int) string {
+	return strings.ToUpper(lo.SnakeCase(http.StatusText(status)))
+}
+
+// addTSErrors declares XRPCClientError, which the generated clients throw for
+// non-2xx responses, along with a type guard and the non-throwing safe helper.
+func addTSErrors(file *internals.TSFile) {
+	file.AddNode(&internals.TSTypeAlias{
+		Name:   "XRPCErrorCode",
+		Export: true,
+		Type: internals.TSUnion(append(
+			lo.Map(tsErrorCodes, func(status int, _ int) string { return fmt.Sprintf("%q", tsErrorCode(status)) }),
+			`"UNKNOWN"`,
+		)...),
+	})
+
+	codes := []string{"const errorCodes: Record<number, XRPCErrorCode> = {"}
+	for _, status := range tsErrorCodes {
+		codes = append(codes, fmt.Sprintf("  %d: %q,", status, tsErrorCode(status)))
+	}
+	file.AddNode(&internals.TSRaw{Lines: append(codes, "};")})
+
+	file.AddNode(&internals.TSRaw{Lines: []string{
+		"/**",
+		" * Thrown for non-2xx responses. The server's {\"detail\": ...} envelope is",
+		" * exposed as detail, validation failures as issues keyed by field, and the",
+		" * id the server logged the call under as requestId.",
+		" */",
+		"export class XRPCClientError extends Error {",
+		"  readonly status: number;",
+		"  readonly code: XRPCErrorCode | (string & {});",
+		"  readonly detail: unknown;",
+		"  readonly issues: Record<string, string>;",
+		"  readonly requestId?: string;",
+		"",
+		"  constructor(status: number, body: unknown, requestId?: string) {",
+		"    const envelope =",
+		"      body !== null && typeof body === \"object\" ? (body as { detail?: unknown; code?: string; request_id?: string }) : {};",
+		"    const detail = \"detail\" in envelope ? envelope.detail : body;",
+		"",
+		"    super(typeof detail === \"string\" && detail ? detail : `xRPC request failed with status ${status}`);",
+		"    this.name = \"XRPCClientError\";",
+		"    this.status = status;",
+		"    this.code = envelope.code ?? errorCodes[status] ?? \"UNKNOWN\";",
+		"    this.detail = detail;",
+		"    this.issues =",
+		"      detail !== null && typeof detail === \"object\" && !Array.isArray(detail)",
+		"        ? Object.fromEntries(Object.entries(detail).map(([field, issue]) => [field, String(issue)]))",
+		"        : {};",
+		"    this.requestId = envelope.request_id || requestId;",
+		"  }",
+		"",
+		"  static async fromResponse(response: Response): Promise<XRPCClientError> {",
+		"    const text = await response.text();",
+		"    let body: unknown = text;",
+		"    try {",
+		"      body = text ? JSON.parse(text) : null;",
+		"    } catch {",
+		"      // Keep the raw text, e.g. an HTML error page from a proxy.",
+		"    }",
+		"    return new XRPCClientError(response.status, body, response.headers.get(\"X-Request-ID\") ?? undefined);",
+		"  }",
+		"}",
+	}})
+
+	file.AddNode(&internals.TSFunction{
+		Name:       "isXRPCClientError",
+		Params:     []internals.TSParam{{Name: "error", Type: "unknown"}},
+		ReturnType: "error is XRPCClientError",
+		Body:       []string{"return error instanceof XRPCClientError;"},
+		Export:     true,
+	})
+
+	file.AddNode(&internals.TSTypeAlias{
+		Name:       "Result",
+		TypeParams: []string{"T", "E = XRPCClientError"},
+		Type:       internals.TSUnion("{ ok: true; data: T; error?: undefined }", "{ ok: false; data?: undefined; error: E }"),
+		Export:     true,
+	})
+
+	file.AddNode(&internals.TSFunction{
+		Name:       "safe",
+		TypeParams: []string{"T"},
+		Params:     []internals.TSParam{{Name: "promise", Type: "Promise<T>"}},
+		ReturnType: "Promise<Result<T, XRPCClientError | Error>>",
+		Body: []string{
+			"try {",
+			"  return { ok: true, data: await promise };",
+			"} catch (error) {",
+			"  return { ok: false, error: error instanceof Error ? error : new Error(String(error)) };",
+			"}",
+		},
+		Export: true,
+		Async:  true,
+		Doc:    "Settles a call without throwing, e.g. const result = await safe(client.post.list(input)).",
+	})
+}
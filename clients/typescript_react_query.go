@@ -0,0 +1,97 @@
+package clients
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/struckchure/xrpc"
+	"github.com/struckchure/xrpc/internals"
+)
+
+// tsQueryKey builds a stable TanStack Query key from the procedure path, with
+// the input appended when given so that keys form a hierarchy:
+// ["post", "list"] matches every ["post", "list", input].
+func tsQueryKey(path string) string {
+	segments := lo.Map(
+		lo.Compact(strings.Split(path, "/")),
+		func(segment string, _ int) string { return fmt.Sprintf("%q", segment) },
+	)
+
+	return "[" + strings.Join(segments, ", ") + ", ...(input === undefined ? [] : [input])] as const"
+}
+
+func GenerateTypeScriptReactQueryClient(cfg TypeScriptClientConfig) error {
+	file := &internals.TSFile{}
+
+	file.AddNode(&internals.TSImport{Module: "ky", Default: "ky"})
+	file.AddNode(&internals.TSImport{
+		Module: "@tanstack/react-query",
+		Names: []string{
+			"useQuery",
+			"useMutation",
+			"type QueryClient",
+			"type UseQueryOptions",
+			"type UseMutationOptions",
+		},
+	})
+
+	queryKeys := []string{"export const queryKeys = {"}
+	hooks := []internals.TSNode{}
+
+	for _, procedure := range addTSClient(file, cfg.Spec, tsTransportKy) {
+		name := procedure.Function
+		inputTypeName, outputTypeName := procedure.InputType, procedure.OutputType
+
+		if procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
+			queryKeys = append(queryKeys, fmt.Sprintf(
+				"  %s: (input?: %s) => %s,", lo.CamelCase(name), inputTypeName, tsQueryKey(procedure.Path),
+			))
+
+			hooks = append(hooks,
+				&internals.TSRaw{Lines: []string{
+					fmt.Sprintf("export function use%sQuery(", name),
+					fmt.Sprintf("  input: %s,", inputTypeName),
+					fmt.Sprintf("  options?: Omit<UseQueryOptions<%s, XRPCClientError>, \"queryKey\" | \"queryFn\">", outputTypeName),
+					") {",
+					fmt.Sprintf("  return useQuery<%s, XRPCClientError>({", outputTypeName),
+					fmt.Sprintf("    queryKey: queryKeys.%s(input),", lo.CamelCase(name)),
+					fmt.Sprintf("    queryFn: () => %s(input),", procedure.Function),
+					"    ...options,",
+					"  });",
+					"}",
+				}},
+				&internals.TSRaw{Lines: []string{
+					fmt.Sprintf("export function invalidate%sQuery(queryClient: QueryClient, input?: %s) {", name, inputTypeName),
+					fmt.Sprintf("  return queryClient.invalidateQueries({ queryKey: queryKeys.%s(input) });", lo.CamelCase(name)),
+					"}",
+				}},
+			)
+		} else {
+			hooks = append(hooks, &internals.TSRaw{Lines: []string{
+				fmt.Sprintf("export function use%sMutation(", name),
+				fmt.Sprintf("  options?: Omit<UseMutationOptions<%s, XRPCClientError, %s>, \"mutationFn\">", outputTypeName, inputTypeName),
+				") {",
+				fmt.Sprintf("  return useMutation<%s, XRPCClientError, %s>({", outputTypeName, inputTypeName),
+				fmt.Sprintf("    mutationFn: (input: %s) => %s(input),", inputTypeName, procedure.Function),
+				"    ...options,",
+				"  });",
+				"}",
+			}})
+		}
+	}
+
+	file.AddNode(&internals.TSRaw{Lines: append(queryKeys, "};")})
+	for _, hook := range hooks {
+		file.AddNode(hook)
+	}
+
+	// Invalidate every query under a router prefix, e.g. invalidateQueries(queryClient, "post").
+	file.AddNode(&internals.TSRaw{Lines: []string{
+		"export function invalidateQueries(queryClient: QueryClient, ...path: string[]) {",
+		"  return queryClient.invalidateQueries({ queryKey: path });",
+		"}",
+	}})
+
+	return writeTSFile(cfg, file)
+}
@@ -0,0 +1,68 @@
+package clients
+
+import (
+	"fmt"
+
+	"github.com/struckchure/xrpc"
+	"github.com/struckchure/xrpc/internals"
+)
+
+func GenerateTypeScriptSWRClient(cfg TypeScriptClientConfig) error {
+	file := &internals.TSFile{}
+
+	file.AddNode(&internals.TSImport{Module: "swr", Default: "useSWR", Names: []string{"type SWRConfiguration"}})
+	file.AddNode(&internals.TSImport{
+		Module:  "swr/mutation",
+		Default: "useSWRMutation",
+		Names:   []string{"type SWRMutationConfiguration"},
+	})
+
+	hooks := []internals.TSNode{}
+
+	for _, procedure := range addTSClient(file, cfg.Spec, tsTransportFetch) {
+		name := procedure.Function
+		inputTypeName, outputTypeName := procedure.InputType, procedure.OutputType
+
+		if procedure.Type == xrpc.XRPCSpecProcedureTypeQuery {
+			// Passing null as the input skips the request, as SWR does for null keys.
+			hooks = append(hooks, &internals.TSFunction{
+				Name: "use" + name + "Query",
+				Params: []internals.TSParam{
+					{Name: "input", Type: internals.TSUnion(inputTypeName, "null")},
+					{Name: "config", Type: internals.TSGeneric("SWRConfiguration", outputTypeName, "XRPCClientError"), Optional: true},
+				},
+				Body: []string{
+					fmt.Sprintf("return useSWR<%s, XRPCClientError>(", outputTypeName),
+					fmt.Sprintf("  input === null ? null : ([%q, input] as const),", procedure.Path),
+					fmt.Sprintf("  ([, data]: readonly [string, %s]) => %s(data),", inputTypeName, procedure.Function),
+					"  config,",
+					");",
+				},
+				Export: true,
+			})
+		} else {
+			hooks = append(hooks, &internals.TSFunction{
+				Name: "use" + name + "Mutation",
+				Params: []internals.TSParam{{
+					Name:     "config",
+					Type:     internals.TSGeneric("SWRMutationConfiguration", outputTypeName, "XRPCClientError", "string", inputTypeName),
+					Optional: true,
+				}},
+				Body: []string{
+					fmt.Sprintf("return useSWRMutation<%s, XRPCClientError, string, %s>(", outputTypeName, inputTypeName),
+					fmt.Sprintf("  %q,", procedure.Path),
+					fmt.Sprintf("  (_key: string, { arg }: { arg: %s }) => %s(arg),", inputTypeName, procedure.Function),
+					"  config,",
+					");",
+				},
+				Export: true,
+			})
+		}
+	}
+
+	for _, hook := range hooks {
+		file.AddNode(hook)
+	}
+
+	return writeTSFile(cfg, file)
+}
@@ -1,22 +1,93 @@
 package xrpc
 
 import (
+	"context"
+	"log/slog"
+
 	"github.com/labstack/echo/v4"
 	"github.com/samber/do"
-	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// localsKeyPrefix namespaces the values of Context.Locals on the echo.Context.
+const localsKeyPrefix = "xrpc.locals."
+
 type Context[T, R any] struct {
 	ec          echo.Context
 	sharedValue map[string]any
+	logger      *slog.Logger
+	tracer      trace.Tracer
 
 	middlewares     []ProcedureCallback[T, R]
 	rootMiddlewares []ProcedureCallback[any, any]
+	rootAuth        []XRPCSpecAuth
+	policyTable     PolicyTable
+	requirePolicies bool
+	cacheStore      CacheStore
 
 	Injector *do.Injector
 	Input    T
 }
 
+// Logger returns the app logger, with the request's route and request id
+// when called while handling a request.
+func (c *Context[T, R]) Logger() *slog.Logger {
+	if c.ec == nil {
+		return c.logger
+	}
+
+	logger := c.logger.With("path", c.ec.Path())
+	if id := c.RequestID(); id != "" {
+		logger = logger.With("request_id", id)
+	}
+
+	return logger
+}
+
+// RequestID returns the X-Request-ID sent by the client, or the one generated
+// for the request when it sent none.
+func (c *Context[T, R]) RequestID() string {
+	if c.ec == nil {
+		return ""
+	}
+
+	return requestID(c.ec)
+}
+
+// Principal returns the caller authenticated by an IApp.Auth or
+// IProcedure.Auth requirement, or nil.
+func (c *Context[T, R]) Principal() *Principal {
+	if c.ec == nil {
+		return nil
+	}
+
+	principal, _ := c.ec.Get(principalKey).(*Principal)
+	return principal
+}
+
+// Context returns the request's context, carrying the current trace span, so
+// outgoing calls made with it are traced beneath the procedure.
+func (c *Context[T, R]) Context() context.Context {
+	if c.ec == nil {
+		return context.Background()
+	}
+
+	return c.ec.Request().Context()
+}
+
+// Invalidate drops the cached responses of the queries at paths, e.g. from a
+// mutation changing what they return. A path ending in * matches every
+// procedure under the prefix, e.g. "/post/*".
+func (c *Context[T, R]) Invalidate(paths ...string) error {
+	for _, path := range paths {
+		if err := c.cacheStore.Invalidate(c.Context(), cacheInvalidationPrefix(path)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (c *Context[T, R]) Header(key string) string {
 	return c.ec.Request().Header.Get(key)
 }
@@ -33,15 +104,27 @@ func (c *Context[T, R]) Redirect(status int, url string) error {
 	return c.ec.Redirect(status, url)
 }
 
+// Locals stores a value under key for the rest of the request, e.g. from a
+// middleware for the handler, or returns the stored value. Values stored
+// outside a request are defaults for every request.
 func (c *Context[T, R]) Locals(key string, value ...interface{}) interface{} {
+	if c.ec == nil {
+		if len(value) > 0 {
+			c.sharedValue[key] = value[0]
+			return value[0]
+		}
+
+		return c.sharedValue[key]
+	}
+
 	if len(value) > 0 {
-		c.sharedValue[key] = value[0]
+		c.ec.Set(localsKeyPrefix+key, value[0])
 		return value[0]
 	}
 
-	if lo.HasKey(c.sharedValue, key) {
-		return c.sharedValue[key]
+	if value := c.ec.Get(localsKeyPrefix + key); value != nil {
+		return value
 	}
 
-	return nil
+	return c.sharedValue[key]
 }
@@ -0,0 +1,95 @@
+package xrpc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/samber/lo"
+)
+
+// EnumDescriptor lists the values a string field accepts. TypeName is the Go
+// type for registered enums, or the struct and field name for enum tags.
+type EnumDescriptor struct {
+	TypeName string   `json:"type_name" yaml:"type_name"`
+	Values   []string `json:"values" yaml:"values"`
+}
+
+var (
+	enumsMu sync.RWMutex
+	enums   = map[reflect.Type][]string{}
+)
+
+// RegisterEnum records the values of a string type, so fields of that type
+// are described as enums in the spec and generated clients. Register enums
+// before the procedures using them:
+//
+//	xrpc.RegisterEnum(StatusDraft, StatusPublished)
+//
+// Registering a type again replaces its values. Clients name enums by their
+// type name alone, so GenerateSpec fails when the spec uses an enum whose
+// name types from different packages registered.
+func RegisterEnum[T ~string](values ...T) {
+	enumsMu.Lock()
+	defer enumsMu.Unlock()
+
+	enums[reflect.TypeFor[T]()] = lo.Uniq(lo.Map(values, func(value T, _ int) string { return string(value) }))
+}
+
+// registeredEnumCollisions reports the names that several registered types
+// share, of those given.
+func registeredEnumCollisions(names []string) error {
+	enumsMu.RLock()
+	defer enumsMu.RUnlock()
+
+	types := map[string][]string{}
+	for t := range enums {
+		types[t.Name()] = append(types[t.Name()], t.String())
+	}
+
+	errs := []error{}
+	for _, name := range names {
+		if len(types[name]) > 1 {
+			slices.Sort(types[name])
+			errs = append(errs, fmt.Errorf("enum %s is registered by several types: %s", name, strings.Join(types[name], ", ")))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// fieldEnum describes the values of a string field, from its enum tag or the
+// registered values of its type, looking through pointers, slices and maps.
+func fieldEnum(owner reflect.Type, field reflect.StructField) *EnumDescriptor {
+	t := field.Type
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.String {
+		return nil
+	}
+
+	if tag := field.Tag.Get("enum"); tag != "" {
+		name := t.Name()
+		if t.PkgPath() == "" {
+			name = owner.Name() + field.Name
+		}
+
+		values := lo.Map(strings.Split(tag, ","), func(value string, _ int) string { return strings.TrimSpace(value) })
+
+		return &EnumDescriptor{TypeName: name, Values: values}
+	}
+
+	enumsMu.RLock()
+	defer enumsMu.RUnlock()
+
+	if values, exists := enums[t]; exists {
+		return &EnumDescriptor{TypeName: t.Name(), Values: values}
+	}
+
+	return nil
+}
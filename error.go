@@ -1,7 +1,12 @@
 package xrpc
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
 )
 
 type XRPCError struct {
@@ -17,3 +22,39 @@ func (e *XRPCError) Error() string {
 
 	return string(out)
 }
+
+// errorJSON writes the {"detail": ...} error envelope, along with the request
+// id so failures reported by clients can be found in the server logs.
+func errorJSON(c echo.Context, code int, detail any) error {
+	return c.JSON(code, echo.Map{"detail": detail, "request_id": requestID(c)})
+}
+
+// errorHandler replaces echo's default error handler, so errors returned
+// from handlers, unknown routes included, use the same envelope.
+func (a *App) errorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	code, detail := http.StatusInternalServerError, any(http.StatusText(http.StatusInternalServerError))
+
+	var xrpcErr *XRPCError
+	var httpErr *echo.HTTPError
+	switch {
+	case errors.As(err, &xrpcErr):
+		code, detail = xrpcErr.Code, xrpcErr.Detail
+	case errors.As(err, &httpErr):
+		code, detail = httpErr.Code, httpErr.Message
+	case errors.Is(err, context.DeadlineExceeded):
+		code, detail = http.StatusServiceUnavailable, "request timed out"
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(code)
+	} else {
+		err = errorJSON(c, code, detail)
+	}
+	if err != nil {
+		a.logger.Error("failed to write error response", "error", err)
+	}
+}
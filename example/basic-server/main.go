@@ -1,10 +1,11 @@
 package main
 
 import (
-	"fmt"
+	"time"
 
 	"github.com/samber/do"
 	"github.com/struckchure/xrpc"
+	"github.com/struckchure/xrpc/clients"
 	"github.com/struckchure/xrpc/validation"
 )
 
@@ -14,10 +15,20 @@ func main() {
 		ServerUrl:       "http://localhost:9090",
 		SpecPath:        "./xrpc.yaml",
 		AutoGenTRPCSpec: true,
+		Explorer:        true,
+		Clients:         clients.Downloads(),
+		CORS:            &xrpc.CORSConfig{AllowOrigins: []string{"http://localhost:5173"}},
+		SecurityHeaders: &xrpc.SecurityHeadersConfig{},
+		MaxBodySize:     1 << 20,
+		Gzip:            true,
+		Timeout:         30 * time.Second,
 	})
 
 	do.Provide(t.Injector(), NewCarService)
 	do.Provide(t.Injector(), NewEngineService)
+	do.ProvideValue[xrpc.APIKeyStore](t.Injector(), xrpc.StaticAPIKeys{
+		"dev-key": {Subject: "1290", Roles: []string{"author"}},
+	})
 
 	t.Use(func(c xrpc.Context[any, any]) error {
 		c.Locals("userId", "1290")
@@ -27,15 +38,19 @@ func main() {
 
 	t.Router("post",
 		xrpc.NewProcedure[ListPostInput, []Post]("list").
+			Describe("Lists posts, newest first.").
+			Tags("posts").
+			RateLimit(xrpc.RateLimit{Rate: 10, Per: time.Second}).
+			Cache(30*time.Second).
 			Use(
 				func(c xrpc.Context[ListPostInput, []Post]) error {
-					fmt.Println("Middleware 1")
+					c.Logger().Info("middleware 1")
 
 					// return &xrpc.XRPCError{Code: 401, Detail: "something went wrong"}
 					return nil
 				},
 				func(c xrpc.Context[ListPostInput, []Post]) error {
-					fmt.Println("Middleware 2")
+					c.Logger().Info("middleware 2")
 
 					c.Locals("m2", true)
 
@@ -47,8 +62,7 @@ func main() {
 				Field("Limit", validation.Int().Max(10)),
 			).
 			Query(func(c xrpc.Context[ListPostInput, []Post]) error {
-				fmt.Println(c.Locals("m2"))
-				fmt.Println(c.Locals("userId"))
+				c.Logger().Info("listing posts", "m2", c.Locals("m2"), "userId", c.Locals("userId"))
 				carService := do.MustInvoke[*CarService](c.Injector)
 				carService.Start()
 
@@ -56,11 +70,20 @@ func main() {
 			}),
 
 		xrpc.NewProcedure[CreatePostInput, *Post]("create").
+			Auth(xrpc.NewAPIKeyAuth(xrpc.APIKeyConfig{})).
+			Authorize(xrpc.RequireRoles("author")).
+			Idempotent().
 			Input(validation.NewValidator().
 				Field("Title", validation.String().MinLength(10)).
 				Field("Content", validation.String().MinLength(10)),
 			).
 			Mutation(func(c xrpc.Context[CreatePostInput, *Post]) error {
+				c.Logger().Info("creating post", "author", c.Principal().Subject)
+
+				if err := c.Invalidate("/post/list"); err != nil {
+					return err
+				}
+
 				return c.Json(201, &Post{})
 			}),
 
@@ -2,23 +2,13 @@ package main
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/struckchure/xrpc"
 	"github.com/struckchure/xrpc/clients"
-	"gopkg.in/yaml.v3"
 )
 
 func main() {
-	spec := xrpc.TRPCSpec{}
-
-	data, err := os.ReadFile("../basic-server/xrpc.yaml")
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-
-	err = yaml.Unmarshal(data, &spec)
+	spec, err := xrpc.LoadSpec("../basic-server/xrpc.yaml")
 	if err != nil {
 		fmt.Println(err)
 		return
@@ -28,14 +18,15 @@ func main() {
 		Spec:    spec,
 		Output:  "./post_service.go",
 		PkgName: "main",
+		Tracing: true,
 	})
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	// client := NewPostServiceClient()
-	// postList, err := client.PostList(ListPostInput{
+	// client := NewPostServiceClient(WithAuthToken("token"))
+	// postList, err := client.PostList(context.Background(), ListPostInput{
 	// 	Skip:  lo.ToPtr(2),
 	// 	Limit: lo.ToPtr(10),
 	// })
@@ -45,7 +36,7 @@ func main() {
 	// }
 	// fmt.Printf("%#v\n", postList)
 
-	// postCreate, err := client.PostCreate(CreatePostInput{
+	// postCreate, err := client.PostCreate(context.Background(), CreatePostInput{
 	// 	Title:   "OneTwoThreeFourFiveSix",
 	// 	Content: "OneTwoThreeFourFiveSix",
 	// })
@@ -55,7 +46,7 @@ func main() {
 	// }
 	// fmt.Printf("%#v\n", postCreate)
 
-	// postGet, err := client.PostGet(GetPostInput{Id: lo.ToPtr(12), AuthorId: lo.ToPtr("id-1")})
+	// postGet, err := client.PostGet(context.Background(), GetPostInput{Id: lo.ToPtr(12), AuthorId: lo.ToPtr("id-1")})
 	// if err != nil {
 	// 	fmt.Println(err)
 	// 	return
@@ -1,12 +1,27 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	resty "github.com/go-resty/resty/v2"
+	otel "go.opentelemetry.io/otel"
+	propagation "go.opentelemetry.io/otel/propagation"
+	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 )
 
+// PostServiceClient calls http://localhost:9090.
+//
+// Request bodies over 1 MiB are rejected with a 413.
+// Requests taking over 30s fail with a 503.
+// Responses are gzip-compressed for clients sending Accept-Encoding: gzip.
+// Browsers may call the server from http://localhost:5173.
 type PostServiceClient struct {
 	client *resty.Client
 }
@@ -17,29 +32,57 @@ func structToQueryParams(input any) (string, error) {
 		return "", fmt.Errorf("failed to marshal input: %w", err)
 	}
 	var mapData map[string]any
-	err = json.Unmarshal(data, &mapData)
-	if err != nil {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&mapData); err != nil {
 		return "", fmt.Errorf("failed to unmarshal JSON: %w", err)
 	}
 	query := url.Values{}
 	for key, value := range mapData {
-		if value == nil {
-			continue
-		}
-		strVal := fmt.Sprintf("%v", value)
-		query.Add(key, strVal)
+		addQueryValue(query, key, value)
 	}
 	return query.Encode(), nil
 }
 
-type MapError map[string]any
+func addQueryValue(query url.Values, key string, value any) {
+	switch value := value.(type) {
+	case nil:
+	case map[string]any:
+		for k, v := range value {
+			addQueryValue(query, key+"["+k+"]", v)
+		}
+	case []any:
+		for _, v := range value {
+			addQueryValue(query, key, v)
+		}
+	default:
+		query.Add(key, fmt.Sprint(value))
+	}
+}
 
-func (m MapError) Error() string {
-	data, err := json.Marshal(m)
-	if err != nil {
-		return fmt.Sprintf("failed to marshal error map: %v", err)
+// XRPCError is returned for non-2xx responses, with the server's {"detail": ...}
+// envelope decoded, or the raw body as Detail when it is not JSON.
+type XRPCError struct {
+	StatusCode int `json:"-"`
+	Detail     any `json:"detail"`
+	// RequestID is the request_id of the envelope, or the X-Request-ID header.
+	RequestID string `json:"request_id"`
+}
+
+func (e *XRPCError) Error() string {
+	return fmt.Sprintf("xrpc: request failed with status %d: %v", e.StatusCode, e.Detail)
+}
+
+// newXRPCError reads the error envelope of a failed response.
+func newXRPCError(status int, header http.Header, body []byte) *XRPCError {
+	xrpcErr := &XRPCError{
+		RequestID:  header.Get("X-Request-ID"),
+		StatusCode: status,
 	}
-	return string(data)
+	if err := json.Unmarshal(body, xrpcErr); err != nil || xrpcErr.Detail == nil {
+		xrpcErr.Detail = string(body)
+	}
+	return xrpcErr
 }
 
 type Post struct {
@@ -53,20 +96,24 @@ type ListPostInput struct {
 	Limit *int `json:"limit"`
 }
 
-func (c *PostServiceClient) PostList(input ListPostInput) (*[]Post, error) {
+// PostList calls /post/list/.
+//
+// Lists posts, newest first.
+func (c *PostServiceClient) PostList(ctx context.Context, input ListPostInput) (*[]Post, error) {
+	var result []Post
 	queryParams, err := structToQueryParams(input)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.client.R().SetQueryString(queryParams).SetError(&MapError{}).SetResult(&[]Post{}).Get("/post/list/")
+	resp, err := c.client.R().SetContext(ctx).SetQueryString(queryParams).SetResult(&result).Get("/post/list/")
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.IsError() {
-		return nil, resp.Error().(*MapError)
+		return nil, newXRPCError(resp.StatusCode(), resp.Header(), resp.Body())
 	}
-	return resp.Result().(*[]Post), nil
+	return &result, nil
 }
 
 type CreatePostInput struct {
@@ -74,16 +121,21 @@ type CreatePostInput struct {
 	Content string `json:"content"`
 }
 
-func (c *PostServiceClient) PostCreate(input CreatePostInput) (*Post, error) {
-	resp, err := c.client.R().SetBody(input).SetError(&MapError{}).SetResult(&Post{}).Post("/post/create/")
+func (c *PostServiceClient) PostCreate(ctx context.Context, input CreatePostInput) (*Post, error) {
+	var result Post
+	idempotencyKey, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.R().SetContext(ctx).SetHeader("Idempotency-Key", idempotencyKey).AddRetryCondition(retryIdempotent).SetBody(input).SetResult(&result).Post("/post/create/")
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.IsError() {
-		return nil, resp.Error().(*MapError)
+		return nil, newXRPCError(resp.StatusCode(), resp.Header(), resp.Body())
 	}
-	return resp.Result().(*Post), nil
+	return &result, nil
 }
 
 type GetPostInput struct {
@@ -91,25 +143,183 @@ type GetPostInput struct {
 	AuthorId string `json:"author_id"`
 }
 
-func (c *PostServiceClient) PostGet(input GetPostInput) (*Post, error) {
+func (c *PostServiceClient) PostGet(ctx context.Context, input GetPostInput) (*Post, error) {
+	var result Post
 	queryParams, err := structToQueryParams(input)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.client.R().SetQueryString(queryParams).SetError(&MapError{}).SetResult(&Post{}).Get("/post/get/")
+	resp, err := c.client.R().SetContext(ctx).SetQueryString(queryParams).SetResult(&result).Get("/post/get/")
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.IsError() {
-		return nil, resp.Error().(*MapError)
+		return nil, newXRPCError(resp.StatusCode(), resp.Header(), resp.Body())
+	}
+	return &result, nil
+}
+
+// RequestInterceptor can inspect or modify every outgoing request.
+type RequestInterceptor func(*http.Request) error
+
+// randomID returns a random hex identifier, e.g. for request IDs and
+// idempotency keys.
+func randomID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// setRequestID identifies each request with a random X-Request-ID, unless one
+// was set, e.g. with WithHeader.
+func setRequestID(req *http.Request) error {
+	if req.Header.Get("X-Request-ID") != "" {
+		return nil
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Request-ID", id)
+	return nil
+}
+
+// retryableStatus reports whether a call failing with status may succeed when
+// retried, including an idempotent mutation still in flight.
+func retryableStatus(status int, idempotent bool) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return idempotent && status == http.StatusConflict
+}
+
+// retryAfter returns the wait requested by a Retry-After header, or zero.
+func retryAfter(header http.Header) time.Duration {
+	seconds, _ := strconv.Atoi(header.Get("Retry-After"))
+	return time.Duration(seconds) * time.Second
+}
+
+// injectTraceContext propagates the trace of the request's context, e.g.
+// a W3C traceparent header, so the server continues the caller's trace.
+func injectTraceContext(req *http.Request) error {
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return nil
+}
+
+type clientOptions struct {
+	baseURL      string
+	httpClient   *http.Client
+	headers      map[string]string
+	authToken    string
+	timeout      time.Duration
+	interceptors []RequestInterceptor
+	retries      int
+}
+
+// Option configures the client returned by the generated constructor.
+type Option func(*clientOptions)
+
+// WithBaseURL overrides the server URL from the spec (http://localhost:9090).
+func WithBaseURL(url string) Option {
+	return func(o *clientOptions) {
+		o.baseURL = url
+	}
+}
+
+// WithHTTPClient sends requests through a custom *http.Client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *clientOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithHeader adds a header to every request.
+func WithHeader(key, value string) Option {
+	return func(o *clientOptions) {
+		o.headers[key] = value
+	}
+}
+
+// WithAuthToken sends the token as an Authorization: Bearer header.
+func WithAuthToken(token string) Option {
+	return func(o *clientOptions) {
+		o.authToken = token
+	}
+}
+
+// WithTimeout bounds the duration of every request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *clientOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetries retries queries and idempotent mutations up to retries times after
+// network errors and 408, 429 or 5xx responses, waiting for any Retry-After.
+// Retried mutations reuse their Idempotency-Key.
+func WithRetries(retries int) Option {
+	return func(o *clientOptions) {
+		o.retries = retries
+	}
+}
+
+// WithRequestInterceptor runs the interceptors, in order, before every request is sent.
+func WithRequestInterceptor(interceptors ...RequestInterceptor) Option {
+	return func(o *clientOptions) {
+		o.interceptors = append(o.interceptors, interceptors...)
 	}
-	return resp.Result().(*Post), nil
 }
 
-func NewPostServiceClient() *PostServiceClient {
+// retryQuery retries queries that may succeed when retried.
+func retryQuery(resp *resty.Response, err error) bool {
+	return resp != nil && resp.Request.Method == http.MethodGet && (err != nil || retryableStatus(resp.StatusCode(), false))
+}
+
+// retryIdempotent retries idempotent mutations that may succeed when retried.
+func retryIdempotent(resp *resty.Response, err error) bool {
+	return resp != nil && (err != nil || retryableStatus(resp.StatusCode(), true))
+}
+
+func NewPostServiceClient(opts ...Option) *PostServiceClient {
+	o := &clientOptions{
+		baseURL:      "http://localhost:9090",
+		headers:      map[string]string{},
+		interceptors: []RequestInterceptor{setRequestID, injectTraceContext},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	client := resty.New()
-	client.SetBaseURL("http://localhost:9090")
+	if o.httpClient != nil {
+		client = resty.NewWithClient(o.httpClient)
+	}
+	client.SetBaseURL(o.baseURL)
+	client.SetHeaders(o.headers)
+	if o.authToken != "" {
+		client.SetAuthToken(o.authToken)
+	}
+	if o.timeout > 0 {
+		client.SetTimeout(o.timeout)
+	}
+	if o.retries > 0 {
+		client.SetRetryCount(o.retries).SetRetryMaxWaitTime(30 * time.Second).SetRetryAfter(func(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+			return retryAfter(resp.Header()), nil
+		}).AddRetryCondition(retryQuery)
+	}
+	client.SetPreRequestHook(func(_ *resty.Client, r *http.Request) error {
+		for _, interceptor := range o.interceptors {
+			if err := interceptor(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 
 	return &PostServiceClient{client: client}
 }
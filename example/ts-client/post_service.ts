@@ -1,39 +1,248 @@
 import ky from "ky";
 
-interface ListPostInput {
-  skip?: number;
-  limit?: number;
+export interface ListPostInput {
+  skip: number | null;
+  limit: number | null;
 }
 
-async function PostList(data: ListPostInput): Promise<Post[]> {
-  const queryParams = new URLSearchParams(data as unknown as Record<string, any>).toString();
-  return await ky.get(`http://localhost:9090/post/list/?${queryParams}`).json<Post[]>();
+export interface Post {
+  id: number;
+  title: string;
+  content: string;
 }
 
-interface CreatePostInput {
+export interface CreatePostInput {
   title: string;
   content: string;
 }
 
-interface Post {
-  content: string;
+export interface GetPostInput {
   id: number;
-  title: string;
+  author_id: string;
 }
 
-async function PostCreate(data: CreatePostInput): Promise<Post> {
-  return await ky.post("http://localhost:9090/post/create/", {
-    json: data
-  }).json<Post>();
+export type XRPCErrorCode = "BAD_REQUEST" | "UNAUTHORIZED" | "FORBIDDEN" | "NOT_FOUND" | "METHOD_NOT_ALLOWED" | "REQUEST_TIMEOUT" | "CONFLICT" | "PRECONDITION_FAILED" | "REQUEST_ENTITY_TOO_LARGE" | "UNPROCESSABLE_ENTITY" | "TOO_MANY_REQUESTS" | "INTERNAL_SERVER_ERROR" | "NOT_IMPLEMENTED" | "BAD_GATEWAY" | "SERVICE_UNAVAILABLE" | "GATEWAY_TIMEOUT" | "UNKNOWN";
+
+const errorCodes: Record<number, XRPCErrorCode> = {
+  400: "BAD_REQUEST",
+  401: "UNAUTHORIZED",
+  403: "FORBIDDEN",
+  404: "NOT_FOUND",
+  405: "METHOD_NOT_ALLOWED",
+  408: "REQUEST_TIMEOUT",
+  409: "CONFLICT",
+  412: "PRECONDITION_FAILED",
+  413: "REQUEST_ENTITY_TOO_LARGE",
+  422: "UNPROCESSABLE_ENTITY",
+  429: "TOO_MANY_REQUESTS",
+  500: "INTERNAL_SERVER_ERROR",
+  501: "NOT_IMPLEMENTED",
+  502: "BAD_GATEWAY",
+  503: "SERVICE_UNAVAILABLE",
+  504: "GATEWAY_TIMEOUT",
+};
+
+/**
+ * Thrown for non-2xx responses. The server's {"detail": ...} envelope is
+ * exposed as detail, validation failures as issues keyed by field, and the
+ * id the server logged the call under as requestId.
+ */
+export class XRPCClientError extends Error {
+  readonly status: number;
+  readonly code: XRPCErrorCode | (string & {});
+  readonly detail: unknown;
+  readonly issues: Record<string, string>;
+  readonly requestId?: string;
+
+  constructor(status: number, body: unknown, requestId?: string) {
+    const envelope =
+      body !== null && typeof body === "object" ? (body as { detail?: unknown; code?: string; request_id?: string }) : {};
+    const detail = "detail" in envelope ? envelope.detail : body;
+
+    super(typeof detail === "string" && detail ? detail : `xRPC request failed with status ${status}`);
+    this.name = "XRPCClientError";
+    this.status = status;
+    this.code = envelope.code ?? errorCodes[status] ?? "UNKNOWN";
+    this.detail = detail;
+    this.issues =
+      detail !== null && typeof detail === "object" && !Array.isArray(detail)
+        ? Object.fromEntries(Object.entries(detail).map(([field, issue]) => [field, String(issue)]))
+        : {};
+    this.requestId = envelope.request_id || requestId;
+  }
+
+  static async fromResponse(response: Response): Promise<XRPCClientError> {
+    const text = await response.text();
+    let body: unknown = text;
+    try {
+      body = text ? JSON.parse(text) : null;
+    } catch {
+      // Keep the raw text, e.g. an HTML error page from a proxy.
+    }
+    return new XRPCClientError(response.status, body, response.headers.get("X-Request-ID") ?? undefined);
+  }
 }
 
-interface GetPostInput {
-  id: number;
-  author_id: string;
+export function isXRPCClientError(error: unknown): error is XRPCClientError {
+  return error instanceof XRPCClientError;
 }
 
-async function PostGet(data: GetPostInput): Promise<Post> {
-  const queryParams = new URLSearchParams(data as unknown as Record<string, any>).toString();
-  return await ky.get(`http://localhost:9090/post/get/?${queryParams}`).json<Post>();
+export type Result<T, E = XRPCClientError> = { ok: true; data: T; error?: undefined } | { ok: false; data?: undefined; error: E };
+
+/**
+ * Settles a call without throwing, e.g. const result = await safe(client.post.list(input)).
+ */
+export async function safe<T>(promise: Promise<T>): Promise<Result<T, XRPCClientError | Error>> {
+  try {
+    return { ok: true, data: await promise };
+  } catch (error) {
+    return { ok: false, error: error instanceof Error ? error : new Error(String(error)) };
+  }
 }
 
+export interface ClientOptions {
+  /**
+   * Defaults to http://localhost:9090.
+   */
+  baseUrl?: string;
+  /**
+   * Sent with every request; pass a function to resolve them per request, e.g. auth tokens.
+   */
+  headers?: Record<string, string> | (() => Record<string, string> | Promise<Record<string, string>>);
+  fetch?: typeof fetch;
+  /**
+   * Injects the trace context into request headers, e.g.
+   * `(headers) => propagation.inject(context.active(), headers)` with OpenTelemetry.
+   * Without it no trace headers are sent.
+   */
+  propagate?: (headers: Record<string, string>) => void;
+  /**
+   * Retries queries and idempotent mutations this many times after network errors and 408, 429 or 5xx
+   * responses, waiting for any Retry-After. Retried mutations reuse their Idempotency-Key. Defaults to 0.
+   */
+  retries?: number;
+  /**
+   * Called with any error, including XRPCClientError for non-2xx responses, before it is rethrown.
+   */
+  onError?: (error: unknown) => void;
+}
+
+/**
+ * Encodes input as query parameters: arrays repeat the key and nested
+ * objects use brackets, e.g. filter[author]=1.
+ */
+function toSearchParams(data: unknown): URLSearchParams {
+  const params = new URLSearchParams();
+  const add = (key: string, value: unknown): void => {
+    if (value === null || value === undefined) return;
+    if (Array.isArray(value)) {
+      value.forEach((item) => add(key, item));
+    } else if (typeof value === "object") {
+      Object.entries(value as Record<string, unknown>).forEach(([k, v]) => add(`${key}[${k}]`, v));
+    } else {
+      params.append(key, String(value));
+    }
+  };
+  Object.entries((data ?? {}) as Record<string, unknown>).forEach(([key, value]) => add(key, value));
+  return params;
+}
+
+/**
+ * Whether a failed call may succeed when retried.
+ */
+function isRetryable(status: number, idempotent: boolean): boolean {
+  return [408, 429, 500, 502, 503, 504].includes(status) || (idempotent && status === 409);
+}
+
+/**
+ * Milliseconds to wait before retrying, any Retry-After or an exponential backoff.
+ */
+function retryDelay(attempt: number, retryAfter?: string | null): number {
+  const seconds = Number(retryAfter);
+  if (seconds > 0) return seconds * 1000;
+  return Math.min(250 * 2 ** attempt, 10_000) * (0.5 + Math.random() / 2);
+}
+
+/**
+ * Request bodies over 1 MiB are rejected with a 413.
+ * Requests taking over 30s fail with a 503.
+ * Responses are gzip-compressed for clients sending Accept-Encoding: gzip.
+ * Browsers may call the server from http://localhost:5173.
+ */
+export function createClient(options: ClientOptions = {}) {
+  const baseUrl = (options.baseUrl ?? "http://localhost:9090").replace(/\/+$/, "");
+
+  async function request<T>(method: "GET" | "POST", path: string, data: unknown, idempotent = false): Promise<T> {
+    const headers: Record<string, string> = {
+      ...(typeof options.headers === "function" ? await options.headers() : options.headers),
+    };
+    if (!headers["X-Request-ID"]) headers["X-Request-ID"] = crypto.randomUUID();
+    if (idempotent && !headers["Idempotency-Key"]) headers["Idempotency-Key"] = crypto.randomUUID();
+    options.propagate?.(headers);
+    const retries = method === "GET" || idempotent ? (options.retries ?? 0) : 0;
+
+    const send = () =>
+      ky(baseUrl + path, {
+        method,
+        headers,
+        fetch: options.fetch,
+        throwHttpErrors: false,
+        retry: 0,
+        ...(method === "GET" ? { searchParams: toSearchParams(data) } : { json: data }),
+      });
+
+    try {
+      for (let attempt = 0; ; attempt++) {
+        const response = await send().catch((error: unknown) => {
+          if (attempt >= retries) throw error;
+          return undefined;
+        });
+        if (response && (attempt >= retries || !isRetryable(response.status, idempotent))) {
+          if (!response.ok) throw await XRPCClientError.fromResponse(response);
+          return (await response.json()) as T;
+        }
+        await new Promise((resolve) => setTimeout(resolve, retryDelay(attempt, response?.headers.get("Retry-After"))));
+      }
+    } catch (error) {
+      options.onError?.(error);
+      throw error;
+    }
+  }
+
+  return {
+    post: {
+      /**
+       * Lists posts, newest first.
+       */
+      list: (input: ListPostInput) => request<Post[]>("GET", "/post/list/", input),
+      create: (input: CreatePostInput) => request<Post>("POST", "/post/create/", input, true),
+      get: (input: GetPostInput) => request<Post>("GET", "/post/get/", input),
+    },
+  };
+}
+
+export type Client = ReturnType<typeof createClient>;
+
+let defaultClient: Client = createClient();
+
+/**
+ * Replaces the client used by the exported procedure functions.
+ */
+export function configureClient(options: ClientOptions) {
+  defaultClient = createClient(options);
+}
+
+/**
+ * Lists posts, newest first.
+ */
+export async function PostList(data: ListPostInput): Promise<Post[]> {
+  return defaultClient.post.list(data);
+}
+
+export async function PostCreate(data: CreatePostInput): Promise<Post> {
+  return defaultClient.post.create(data);
+}
+
+export async function PostGet(data: GetPostInput): Promise<Post> {
+  return defaultClient.post.get(data);
+}
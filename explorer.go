@@ -0,0 +1,71 @@
+package xrpc
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// ClientGenerator renders a client for the spec, see clients.Downloads.
+type ClientGenerator func(TRPCSpec) (string, error)
+
+//go:embed explorer.html
+var explorerHTML string
+
+// serveSpec exposes the live spec at /xrpc/spec.json and /xrpc/spec.yaml, and
+// the API explorer at /xrpc/.
+func (a *App) serveSpec(clients map[string]ClientGenerator) {
+	names := lo.Keys(clients)
+	sort.Strings(names)
+
+	clientNames, _ := json.Marshal(names)
+	page := strings.Replace(explorerHTML, "__XRPC_CLIENTS__", string(clientNames), 1)
+
+	a.Get(Route{
+		path:    "/xrpc/",
+		handler: func(c echo.Context) error { return c.HTML(http.StatusOK, page) },
+	})
+
+	a.Get(Route{
+		path:    "/xrpc/spec.json/",
+		handler: func(c echo.Context) error { return c.JSON(http.StatusOK, a.spec) },
+	})
+
+	a.Get(Route{
+		path: "/xrpc/spec.yaml/",
+		handler: func(c echo.Context) error {
+			out, err := yaml.Marshal(&a.spec)
+			if err != nil {
+				return err
+			}
+
+			return c.Blob(http.StatusOK, "application/yaml", out)
+		},
+	})
+}
+
+// serveClients generates clients on demand at /xrpc/<name>, e.g.
+// /xrpc/client.ts, so they always match the running server.
+func (a *App) serveClients(clients map[string]ClientGenerator) {
+	for name, generate := range clients {
+		a.Get(Route{
+			path: JoinPath("xrpc", name),
+			handler: func(c echo.Context) error {
+				out, err := generate(a.spec)
+				if err != nil {
+					return errorJSON(c, http.StatusInternalServerError, err.Error())
+				}
+
+				c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name))
+				return c.Blob(http.StatusOK, echo.MIMETextPlainCharsetUTF8, []byte(out))
+			},
+		})
+	}
+}
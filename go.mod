@@ -9,7 +9,21 @@ require (
 	gopkg.in/yaml.v3 v3.0.1
 )
 
-require github.com/samber/do v1.6.0
+require (
+	github.com/golang-jwt/jwt/v5 v5.2.2
+	github.com/samber/do v1.6.0
+	go.opentelemetry.io/otel v1.35.0
+	go.opentelemetry.io/otel/sdk v1.35.0
+	go.opentelemetry.io/otel/trace v1.35.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.35.0 // indirect
+)
 
 require (
 	github.com/dave/jennifer v1.7.1
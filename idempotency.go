@@ -0,0 +1,214 @@
+package xrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// IdempotentResponse is a response stored for replay, along with the
+// fingerprint of the request that produced it.
+type IdempotentResponse struct {
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyStore records the outcome of calls made with an Idempotency-Key,
+// so a retry replays the stored response instead of running the handler.
+type IdempotencyStore interface {
+	// Begin returns the response stored at key, or otherwise reserves the
+	// key for lockTTL and reports whether it did, failing while another call
+	// holds the reservation. The reservation expires after lockTTL in case
+	// the call never completes, e.g. when the server stops.
+	Begin(ctx context.Context, key string, lockTTL time.Duration) (response *IdempotentResponse, reserved bool, err error)
+	// Complete stores the response of the call holding the reservation for
+	// ttl.
+	Complete(ctx context.Context, key string, response IdempotentResponse, ttl time.Duration) error
+	// Release drops the reservation without storing a response, so the call
+	// can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+type IdempotencyConfig struct {
+	// TTL keeps responses for replay, defaults to 24 hours.
+	TTL time.Duration
+	// LockTTL holds the key of a call in progress, which repeated calls fail
+	// with a 409 meanwhile, defaults to a minute. Calls taking longer may be
+	// run twice.
+	LockTTL time.Duration
+	// Store defaults to a MemoryIdempotencyStore.
+	Store IdempotencyStore
+	// Required rejects calls without an Idempotency-Key.
+	Required bool
+}
+
+// MemoryIdempotencyStore keeps responses in memory, dropping expired ones.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]idempotencyEntry
+	swept   time.Time
+}
+
+type idempotencyEntry struct {
+	response *IdempotentResponse
+	expires  time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: map[string]idempotencyEntry{}, swept: time.Now()}
+}
+
+func (s *MemoryIdempotencyStore) Begin(_ context.Context, key string, lockTTL time.Duration) (*IdempotentResponse, bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.swept) > time.Minute {
+		for key, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, key)
+			}
+		}
+		s.swept = now
+	}
+
+	if entry, exists := s.entries[key]; exists && now.Before(entry.expires) {
+		return entry.response, false, nil
+	}
+
+	s.entries[key] = idempotencyEntry{expires: now.Add(lockTTL)}
+
+	return nil, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, key string, response IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = idempotencyEntry{response: &response, expires: time.Now().Add(ttl)}
+
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+type idempotency struct {
+	cfg IdempotencyConfig
+}
+
+func newIdempotency(cfg IdempotencyConfig) *idempotency {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = time.Minute
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryIdempotencyStore()
+	}
+
+	return &idempotency{cfg: cfg}
+}
+
+// readBody buffers the request body for begin, leaving it in place to be
+// bound to the input.
+func (i *idempotency) readBody(c echo.Context) ([]byte, error) {
+	body, err := io.ReadAll(c.Request().Body)
+	if errors.Is(err, echo.ErrStatusRequestEntityTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, &XRPCError{Code: http.StatusBadRequest, Detail: "failed to read request body"}
+	}
+	c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// begin replays the stored response of a repeated call and returns true, or
+// records the response of a first call for replay once it is handled. It runs
+// after validation and policies, so only authorized calls are replayed.
+// Keys are scoped to the procedure and the caller, and reusing one with a
+// different body is rejected.
+func (i *idempotency) begin(c echo.Context, body []byte) (bool, error) {
+	key := c.Request().Header.Get(HeaderIdempotencyKey)
+	if key == "" {
+		if i.cfg.Required {
+			return false, &XRPCError{Code: http.StatusBadRequest, Detail: "Idempotency-Key header is required"}
+		}
+		return false, nil
+	}
+
+	sum := sha256.Sum256(body)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	storeKey := c.Path() + ":" + callerScope(c) + ":" + key
+
+	ctx := c.Request().Context()
+	stored, reserved, err := i.cfg.Store.Begin(ctx, storeKey, i.cfg.LockTTL)
+	switch {
+	case err != nil:
+		return false, err
+	case stored != nil && stored.Fingerprint != fingerprint:
+		return false, &XRPCError{Code: http.StatusUnprocessableEntity, Detail: "Idempotency-Key was used with a different request"}
+	case stored != nil:
+		c.Response().Header().Set("Idempotent-Replayed", "true")
+		return true, c.Blob(stored.Status, stored.ContentType, stored.Body)
+	case !reserved:
+		return false, &XRPCError{Code: http.StatusConflict, Detail: "a request with this Idempotency-Key is in progress"}
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+	c.Response().Writer = recorder
+
+	// Server errors are not stored, so retries can succeed.
+	onDone(c, func() {
+		c.Response().Writer = recorder.ResponseWriter
+
+		status := c.Response().Status
+		if !c.Response().Committed || status >= http.StatusInternalServerError {
+			i.cfg.Store.Release(context.WithoutCancel(ctx), storeKey)
+			return
+		}
+
+		i.cfg.Store.Complete(context.WithoutCancel(ctx), storeKey, IdempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: c.Response().Header().Get(echo.HeaderContentType),
+			Body:        recorder.body.Bytes(),
+		}, i.cfg.TTL)
+	})
+
+	return false, nil
+}
+
+// responseRecorder keeps a copy of the response body.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
@@ -0,0 +1,132 @@
+package xrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/struckchure/xrpc/validation"
+)
+
+type idempotencyInput struct {
+	Title string `json:"title"`
+}
+
+// newIdempotencyTestApp serves post/create, which blocks while release is
+// open, and counts the calls it handles.
+func newIdempotencyTestApp(cfg IdempotencyConfig, release chan struct{}) (IApp, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	keys := StaticAPIKeys{
+		"alice": {Subject: "alice", Roles: []string{"author"}},
+		"bob":   {Subject: "bob", Roles: []string{"author"}},
+		"eve":   {Subject: "eve"},
+	}
+
+	app := NewXRPC(XRPCConfig{Name: "Idempotency", AutoGenTRPCSpec: false})
+	app.Router("post", NewProcedure[idempotencyInput, string]("create").
+		Auth(NewAPIKeyAuth(APIKeyConfig{Store: keys})).
+		Authorize(RequireRoles("author")).
+		Idempotent(cfg).
+		Input(validation.NewValidator().Field("Title", validation.String().MinLength(1))).
+		Mutation(func(c Context[idempotencyInput, string]) error {
+			n := calls.Add(1)
+			if release != nil {
+				<-release
+			}
+			return c.Json(http.StatusCreated, c.Input.Title+":"+strconv.Itoa(int(n)))
+		}))
+
+	return app, calls
+}
+
+func idempotencyTestRequest(app IApp, apiKey string, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/post/create/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-API-Key", apiKey)
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	rec := httptest.NewRecorder()
+	app.Server().ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestIdempotency(t *testing.T) {
+	app, calls := newIdempotencyTestApp(IdempotencyConfig{}, nil)
+
+	for _, step := range []struct {
+		name     string
+		apiKey   string
+		key      string
+		body     string
+		status   int
+		response string
+		replayed bool
+	}{
+		{name: "first call", apiKey: "alice", key: "k1", body: `{"title":"a"}`, status: http.StatusCreated, response: `"a:1"`},
+		{name: "replay", apiKey: "alice", key: "k1", body: `{"title":"a"}`, status: http.StatusCreated, response: `"a:1"`, replayed: true},
+		{name: "different body", apiKey: "alice", key: "k1", body: `{"title":"b"}`, status: http.StatusUnprocessableEntity},
+		{name: "other caller", apiKey: "bob", key: "k1", body: `{"title":"a"}`, status: http.StatusCreated, response: `"a:2"`},
+		{name: "caller failing the policy", apiKey: "eve", key: "k1", body: `{"title":"a"}`, status: http.StatusForbidden},
+		{name: "invalid input", apiKey: "alice", key: "k1", body: `{"title":""}`, status: http.StatusBadRequest},
+		{name: "no key", apiKey: "alice", body: `{"title":"a"}`, status: http.StatusCreated, response: `"a:3"`},
+		{name: "no key again", apiKey: "alice", body: `{"title":"a"}`, status: http.StatusCreated, response: `"a:4"`},
+	} {
+		rec := idempotencyTestRequest(app, step.apiKey, step.key, step.body)
+
+		if rec.Code != step.status {
+			t.Errorf("%s: status %d, want %d: %s", step.name, rec.Code, step.status, rec.Body)
+			continue
+		}
+		if step.response != "" && strings.TrimSpace(rec.Body.String()) != step.response {
+			t.Errorf("%s: body %s, want %s", step.name, rec.Body, step.response)
+		}
+		if replayed := rec.Header().Get("Idempotent-Replayed") == "true"; replayed != step.replayed {
+			t.Errorf("%s: replayed %t, want %t", step.name, replayed, step.replayed)
+		}
+	}
+
+	if calls.Load() != 4 {
+		t.Errorf("handler ran %d times, want 4", calls.Load())
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	release := make(chan struct{})
+	app, calls := newIdempotencyTestApp(IdempotencyConfig{LockTTL: 50 * time.Millisecond}, release)
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- idempotencyTestRequest(app, "alice", "k1", `{"title":"a"}`) }()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if rec := idempotencyTestRequest(app, "alice", "k1", `{"title":"a"}`); rec.Code != http.StatusConflict {
+		t.Errorf("call in flight: status %d, want 409: %s", rec.Code, rec.Body)
+	}
+
+	// Once the lock expires, a retry runs again although the first call has
+	// not completed.
+	time.Sleep(60 * time.Millisecond)
+	second := make(chan *httptest.ResponseRecorder)
+	go func() { second <- idempotencyTestRequest(app, "alice", "k1", `{"title":"a"}`) }()
+	for calls.Load() == 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	for _, rec := range []*httptest.ResponseRecorder{<-first, <-second} {
+		if rec.Code != http.StatusCreated {
+			t.Errorf("status %d, want 201: %s", rec.Code, rec.Body)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2", calls.Load())
+	}
+}
@@ -3,7 +3,10 @@ package internals
 import (
 	"bytes"
 	"fmt"
+	"regexp"
 	"strings"
+
+	"github.com/samber/lo"
 )
 
 type TSNode interface {
@@ -12,6 +15,10 @@ type TSNode interface {
 
 type TSFile struct {
 	nodes []TSNode
+
+	// Formatter, when set, post-processes the rendered source, e.g. by piping
+	// it through prettier.
+	Formatter func(string) (string, error)
 }
 
 func (f *TSFile) AddNode(node TSNode) {
@@ -20,12 +27,86 @@ func (f *TSFile) AddNode(node TSNode) {
 
 func (f *TSFile) Render() string {
 	var buf bytes.Buffer
-	for _, node := range f.nodes {
-		buf.WriteString(node.Render() + "\n\n")
+	for i, node := range f.nodes {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(node.Render() + "\n")
 	}
 	return buf.String()
 }
 
+// Format renders the file and runs it through the Formatter, if any.
+func (f *TSFile) Format() (string, error) {
+	out := f.Render()
+	if f.Formatter == nil {
+		return out, nil
+	}
+
+	return f.Formatter(out)
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// TSPropertyName quotes names that are not valid identifiers, e.g. "first-name".
+func TSPropertyName(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+
+	return fmt.Sprintf("%q", name)
+}
+
+// TSUnion joins types into a union, dropping duplicates while keeping order.
+func TSUnion(types ...string) string {
+	return strings.Join(lo.Uniq(types), " | ")
+}
+
+// TSGeneric applies type arguments to a generic type, e.g. Promise<Post>.
+func TSGeneric(name string, args ...string) string {
+	if len(args) == 0 {
+		return name
+	}
+
+	return fmt.Sprintf("%s<%s>", name, strings.Join(args, ", "))
+}
+
+// TSArray wraps unions in parentheses so the suffix applies to the whole type.
+func TSArray(typ string) string {
+	if strings.Contains(typ, " ") {
+		return "(" + typ + ")[]"
+	}
+
+	return typ + "[]"
+}
+
+// TSDoc renders a JSDoc comment, with each line prefixed by indent.
+func TSDoc(doc string, indent string) string {
+	if doc == "" {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(indent + "/**\n")
+	for _, line := range strings.Split(strings.TrimSpace(doc), "\n") {
+		sb.WriteString(strings.TrimRight(indent+" * "+line, " ") + "\n")
+	}
+	sb.WriteString(indent + " */\n")
+	return sb.String()
+}
+
+func renderHead(export bool, doc string) string {
+	return TSDoc(doc, "") + lo.Ternary(export, "export ", "")
+}
+
+func renderTypeParams(params []string) string {
+	if len(params) == 0 {
+		return ""
+	}
+
+	return "<" + strings.Join(params, ", ") + ">"
+}
+
 type TSImport struct {
 	Default string   // e.g., "ky"
 	Names   []string // named imports like "useState", "useEffect"
@@ -46,39 +127,108 @@ func (i *TSImport) Render() string {
 	return fmt.Sprintf("import %s from \"%s\";", strings.Join(parts, ", "), i.Module)
 }
 
+type TSField struct {
+	Name     string
+	Type     string
+	Optional bool
+	Doc      string
+}
+
+func (f TSField) render(indent string) string {
+	return fmt.Sprintf(
+		"%s%s%s%s: %s;",
+		TSDoc(f.Doc, indent), indent, TSPropertyName(f.Name), lo.Ternary(f.Optional, "?", ""), f.Type,
+	)
+}
+
 type TSInterface struct {
-	Name   string
-	Fields map[string]string
+	Name       string
+	TypeParams []string
+	Extends    []string
+	Fields     []TSField
+	Export     bool
+	Doc        string
 }
 
 func (iface *TSInterface) Render() string {
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("interface %s {\n", iface.Name))
-	for field, typ := range iface.Fields {
-		sb.WriteString(fmt.Sprintf("  %s: %s;\n", field, typ))
+	sb.WriteString(renderHead(iface.Export, iface.Doc))
+	sb.WriteString("interface " + iface.Name + renderTypeParams(iface.TypeParams))
+	if len(iface.Extends) > 0 {
+		sb.WriteString(" extends " + strings.Join(iface.Extends, ", "))
+	}
+	sb.WriteString(" {\n")
+	for _, field := range iface.Fields {
+		sb.WriteString(field.render("  ") + "\n")
 	}
 	sb.WriteString("}")
 	return sb.String()
 }
 
+type TSTypeAlias struct {
+	Name       string
+	TypeParams []string
+	Type       string
+	Export     bool
+	Doc        string
+}
+
+func (alias *TSTypeAlias) Render() string {
+	return fmt.Sprintf(
+		"%stype %s%s = %s;",
+		renderHead(alias.Export, alias.Doc), alias.Name, renderTypeParams(alias.TypeParams), alias.Type,
+	)
+}
+
+type TSParam struct {
+	Name     string
+	Type     string
+	Optional bool
+	Default  string
+}
+
+func (p TSParam) render() string {
+	out := p.Name + lo.Ternary(p.Optional && p.Default == "", "?", "") + ": " + p.Type
+	if p.Default != "" {
+		out += " = " + p.Default
+	}
+
+	return out
+}
+
 type TSFunction struct {
 	Name       string
+	TypeParams []string
+	Params     []TSParam
 	ReturnType string
-	Params     map[string]string
 	Body       []string
+	Export     bool
+	Async      bool
+	Doc        string
 }
 
 func (fn *TSFunction) Render() string {
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("async function %s(", fn.Name))
-	paramList := []string{}
-	for param, typ := range fn.Params {
-		paramList = append(paramList, fmt.Sprintf("%s: %s", param, typ))
+	sb.WriteString(renderHead(fn.Export, fn.Doc))
+	sb.WriteString(lo.Ternary(fn.Async, "async ", "") + "function " + fn.Name + renderTypeParams(fn.TypeParams) + "(")
+	sb.WriteString(strings.Join(lo.Map(fn.Params, func(p TSParam, _ int) string { return p.render() }), ", ") + ")")
+	if fn.ReturnType != "" {
+		sb.WriteString(": " + fn.ReturnType)
 	}
-	sb.WriteString(strings.Join(paramList, ", ") + "): " + fn.ReturnType + " {\n")
+	sb.WriteString(" {\n")
 	for _, stmt := range fn.Body {
-		sb.WriteString("  " + stmt + "\n")
+		sb.WriteString(strings.TrimRight("  "+stmt, " ") + "\n")
 	}
 	sb.WriteString("}")
 	return sb.String()
 }
+
+// TSRaw renders pre-formatted lines verbatim, for constructs the other nodes
+// do not model.
+type TSRaw struct {
+	Lines []string
+}
+
+func (r *TSRaw) Render() string {
+	return strings.Join(r.Lines, "\n")
+}
@@ -0,0 +1,77 @@
+package xrpc
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	procedureTypeKey   = "xrpc.procedure_type"
+	validationErrorKey = "xrpc.validation_error"
+)
+
+// procedureType tags requests with the procedure type for the access log.
+func procedureType(t XRPCSpecProcedureType) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(procedureTypeKey, t)
+			return next(c)
+		}
+	}
+}
+
+// requestID returns the id assigned by the request id middleware, falling
+// back to the one sent by the client.
+func requestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
+
+// accessLog writes one record per request once the response is written,
+// at warn level for 4xx and error level for 5xx responses.
+func accessLog(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			attrs := []slog.Attr{
+				slog.String("method", c.Request().Method),
+				slog.String("path", c.Request().URL.Path),
+				slog.Int("status", status),
+				slog.Duration("latency", time.Since(start)),
+			}
+			if t, ok := c.Get(procedureTypeKey).(XRPCSpecProcedureType); ok {
+				attrs = append(attrs, slog.String("type", string(t)))
+			}
+			if id := requestID(c); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+			if err := c.Get(validationErrorKey); err != nil {
+				attrs = append(attrs, slog.Any("validation", err))
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case status >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+
+			logger.LogAttrs(c.Request().Context(), level, "request", attrs...)
+
+			return nil
+		}
+	}
+}
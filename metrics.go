@@ -0,0 +1,198 @@
+package xrpc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// metricsBuckets are the latency histogram bounds in seconds, matching the
+// Prometheus client defaults.
+var metricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Metrics records request counts, errors, validation failures, latency and
+// in-flight requests per procedure, and renders them in the Prometheus text
+// exposition format.
+type Metrics struct {
+	mu         sync.Mutex
+	procedures map[metricsKey]*procedureMetrics
+}
+
+type metricsKey struct {
+	path string
+	typ  XRPCSpecProcedureType
+}
+
+type procedureMetrics struct {
+	requests           uint64
+	validationFailures uint64
+	errors             map[int]uint64
+	inFlight           int64
+	buckets            []uint64
+	count              uint64
+	sum                float64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{procedures: map[metricsKey]*procedureMetrics{}}
+}
+
+// middleware records the requests of one procedure. Its series are created
+// up front so they are exported as zero before the first request.
+func (m *Metrics) middleware(path string, t XRPCSpecProcedureType) echo.MiddlewareFunc {
+	m.mu.Lock()
+	pm, exists := m.procedures[metricsKey{path, t}]
+	if !exists {
+		pm = &procedureMetrics{errors: map[int]uint64{}, buckets: make([]uint64, len(metricsBuckets))}
+		m.procedures[metricsKey{path, t}] = pm
+	}
+	m.mu.Unlock()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			m.mu.Lock()
+			pm.inFlight++
+			m.mu.Unlock()
+
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			elapsed := time.Since(start).Seconds()
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			pm.inFlight--
+			pm.requests++
+			if status := c.Response().Status; status >= http.StatusBadRequest {
+				pm.errors[status]++
+			}
+			if c.Get(validationErrorKey) != nil {
+				pm.validationFailures++
+			}
+
+			pm.count++
+			pm.sum += elapsed
+			for i, bound := range metricsBuckets {
+				if elapsed <= bound {
+					pm.buckets[i]++
+					break
+				}
+			}
+
+			return nil
+		}
+	}
+}
+
+// WriteTo renders the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]metricsKey, 0, len(m.procedures))
+	for key := range m.procedures {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].path != keys[j].path {
+			return keys[i].path < keys[j].path
+		}
+		return keys[i].typ < keys[j].typ
+	})
+
+	counter := &countingWriter{w: bufio.NewWriter(w)}
+	family := func(name, kind, help string, sample func(key metricsKey, labels string, pm *procedureMetrics)) {
+		fmt.Fprintf(counter, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, key := range keys {
+			labels := fmt.Sprintf(`path="%s",type="%s"`, metricsLabelEscaper.Replace(key.path), metricsLabelEscaper.Replace(string(key.typ)))
+			sample(key, labels, m.procedures[key])
+		}
+	}
+
+	family("xrpc_requests_total", "counter", "Requests handled, by procedure.", func(_ metricsKey, labels string, pm *procedureMetrics) {
+		fmt.Fprintf(counter, "xrpc_requests_total{%s} %d\n", labels, pm.requests)
+	})
+
+	family("xrpc_errors_total", "counter", "Responses with a 4xx or 5xx status, by procedure and status code.", func(_ metricsKey, labels string, pm *procedureMetrics) {
+		codes := make([]int, 0, len(pm.errors))
+		for code := range pm.errors {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+
+		for _, code := range codes {
+			fmt.Fprintf(counter, "xrpc_errors_total{%s,code=\"%d\"} %d\n", labels, code, pm.errors[code])
+		}
+	})
+
+	family("xrpc_validation_failures_total", "counter", "Requests rejected by input binding or validation, by procedure.", func(_ metricsKey, labels string, pm *procedureMetrics) {
+		fmt.Fprintf(counter, "xrpc_validation_failures_total{%s} %d\n", labels, pm.validationFailures)
+	})
+
+	family("xrpc_requests_in_flight", "gauge", "Requests currently being handled, by procedure.", func(_ metricsKey, labels string, pm *procedureMetrics) {
+		fmt.Fprintf(counter, "xrpc_requests_in_flight{%s} %d\n", labels, pm.inFlight)
+	})
+
+	family("xrpc_request_duration_seconds", "histogram", "Request latency in seconds, by procedure.", func(_ metricsKey, labels string, pm *procedureMetrics) {
+		cumulative := uint64(0)
+		for i, bound := range metricsBuckets {
+			cumulative += pm.buckets[i]
+			fmt.Fprintf(counter, "xrpc_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(counter, "xrpc_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, pm.count)
+		fmt.Fprintf(counter, "xrpc_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(pm.sum, 'g', -1, 64))
+		fmt.Fprintf(counter, "xrpc_request_duration_seconds_count{%s} %d\n", labels, pm.count)
+	})
+
+	if counter.err == nil {
+		counter.err = counter.w.Flush()
+	}
+
+	return counter.n, counter.err
+}
+
+// countingWriter tracks the bytes written and the first error, so WriteTo
+// can report them after a series of Fprintf calls.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+
+	return n, err
+}
+
+// serveMetrics exposes the metrics at /metrics.
+func (a *App) serveMetrics() {
+	a.Get(Route{
+		path: "/metrics/",
+		handler: func(c echo.Context) error {
+			c.Response().Header().Set(echo.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+			c.Response().WriteHeader(http.StatusOK)
+
+			_, err := a.metrics.WriteTo(c.Response())
+			return err
+		},
+	})
+}
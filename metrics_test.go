@@ -0,0 +1,82 @@
+package xrpc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/struckchure/xrpc/validation"
+)
+
+type metricsInput struct {
+	Name string `json:"name"`
+}
+
+func TestMetricsExposition(t *testing.T) {
+	app := NewXRPC(XRPCConfig{Name: "Metrics", AutoGenTRPCSpec: false, Metrics: true})
+	app.Router("greeting",
+		NewProcedure[metricsInput, string]("hello").
+			Input(validation.NewValidator().Field("Name", validation.String().Required())).
+			Mutation(func(c Context[metricsInput, string]) error {
+				if c.Input.Name == "fail" {
+					return errors.New("greeting failed")
+				}
+				return c.Json(http.StatusOK, "hello "+c.Input.Name)
+			}),
+	)
+
+	for _, body := range []string{`{"name":"ada"}`, `{"name":"ada"}`, `{}`, `{"name":"fail"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/greeting/hello/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		app.Server().ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	app.Server().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("content type %q, want the Prometheus text format", got)
+	}
+
+	lines := strings.Split(rec.Body.String(), "\n")
+	for _, want := range []string{
+		`# TYPE xrpc_requests_total counter`,
+		`xrpc_requests_total{path="/greeting/hello/",type="Mutation"} 4`,
+		`# TYPE xrpc_errors_total counter`,
+		`xrpc_errors_total{path="/greeting/hello/",type="Mutation",code="400"} 1`,
+		`xrpc_errors_total{path="/greeting/hello/",type="Mutation",code="500"} 1`,
+		`xrpc_validation_failures_total{path="/greeting/hello/",type="Mutation"} 1`,
+		`xrpc_requests_in_flight{path="/greeting/hello/",type="Mutation"} 0`,
+		`# TYPE xrpc_request_duration_seconds histogram`,
+		`xrpc_request_duration_seconds_bucket{path="/greeting/hello/",type="Mutation",le="+Inf"} 4`,
+		`xrpc_request_duration_seconds_count{path="/greeting/hello/",type="Mutation"} 4`,
+	} {
+		found := false
+		for _, line := range lines {
+			found = found || line == want
+		}
+		if !found {
+			t.Errorf("missing line %q in:\n%s", want, rec.Body)
+		}
+	}
+
+	// The buckets are cumulative, so each one counts at least the requests of
+	// the bucket before it.
+	previous := -1
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "xrpc_request_duration_seconds_bucket{") {
+			continue
+		}
+		var count int
+		if _, err := fmt.Sscanf(line[strings.LastIndex(line, " ")+1:], "%d", &count); err != nil || count < previous {
+			t.Errorf("bucket line %q is not cumulative", line)
+		}
+		previous = count
+	}
+}
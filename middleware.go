@@ -0,0 +1,102 @@
+package xrpc
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/samber/lo"
+)
+
+// corsAllowHeaders are the request headers xRPC clients send.
+var corsAllowHeaders = []string{
+	echo.HeaderContentType, echo.HeaderAuthorization, "X-API-Key", echo.HeaderXRequestID,
+	HeaderIdempotencyKey, "If-None-Match", "traceparent", "tracestate", "baggage",
+}
+
+// corsExposeHeaders are the response headers xRPC clients read.
+var corsExposeHeaders = []string{
+	echo.HeaderXRequestID, echo.HeaderRetryAfter, "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset",
+	"ETag", "X-Cache", "Idempotent-Replayed", "Deprecation", "Sunset",
+}
+
+type CORSConfig struct {
+	// AllowOrigins defaults to every origin, "*".
+	AllowOrigins []string
+	// AllowHeaders are allowed in addition to the headers xRPC clients send,
+	// e.g. the header of an API key auth other than X-API-Key.
+	AllowHeaders []string
+	// AllowCredentials lets browsers send cookies. It requires explicit
+	// AllowOrigins, and is ignored with an error log when they are empty or
+	// "*".
+	AllowCredentials bool
+	// MaxAge caches preflight responses, defaults to 10 minutes.
+	MaxAge time.Duration
+}
+
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge sends Strict-Transport-Security with HTTPS responses, unless
+	// zero.
+	HSTSMaxAge time.Duration
+	// ContentSecurityPolicy is sent when set. A strict policy breaks the
+	// explorer.
+	ContentSecurityPolicy string
+	// ReferrerPolicy defaults to no-referrer.
+	ReferrerPolicy string
+}
+
+// serverMiddlewares applies the CORS, security header, body size, compression
+// and timeout settings of the config to every route.
+func serverMiddlewares(cfg XRPCConfig) []echo.MiddlewareFunc {
+	middlewares := []echo.MiddlewareFunc{}
+
+	if cfg.CORS != nil {
+		middlewares = append(middlewares, middleware.CORSWithConfig(middleware.CORSConfig{
+			AllowOrigins:     cfg.CORS.AllowOrigins,
+			AllowMethods:     []string{echo.GET, echo.HEAD, echo.POST},
+			AllowHeaders:     append(append([]string{}, corsAllowHeaders...), cfg.CORS.AllowHeaders...),
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			ExposeHeaders:    corsExposeHeaders,
+			MaxAge:           int(cfg.CORS.MaxAge.Seconds()),
+		}))
+	}
+
+	if cfg.SecurityHeaders != nil {
+		middlewares = append(middlewares, middleware.SecureWithConfig(middleware.SecureConfig{
+			ContentTypeNosniff:    "nosniff",
+			XFrameOptions:         "DENY",
+			HSTSMaxAge:            int(cfg.SecurityHeaders.HSTSMaxAge.Seconds()),
+			ContentSecurityPolicy: cfg.SecurityHeaders.ContentSecurityPolicy,
+			ReferrerPolicy:        lo.CoalesceOrEmpty(cfg.SecurityHeaders.ReferrerPolicy, "no-referrer"),
+		}))
+	}
+
+	if cfg.MaxBodySize > 0 {
+		middlewares = append(middlewares, middleware.BodyLimit(strconv.FormatInt(cfg.MaxBodySize, 10)))
+	}
+
+	if cfg.Gzip {
+		middlewares = append(middlewares, middleware.GzipWithConfig(middleware.GzipConfig{MinLength: 1024}))
+	}
+
+	if cfg.Timeout > 0 {
+		middlewares = append(middlewares, middleware.ContextTimeout(cfg.Timeout))
+	}
+
+	return middlewares
+}
+
+// specServer describes the settings clients should know about, if any.
+func specServer(cfg XRPCConfig) *XRPCSpecServer {
+	if cfg.CORS == nil && cfg.MaxBodySize <= 0 && !cfg.Gzip && cfg.Timeout <= 0 {
+		return nil
+	}
+
+	server := &XRPCSpecServer{MaxBodySize: cfg.MaxBodySize, Timeout: ceilSeconds(cfg.Timeout), Gzip: cfg.Gzip}
+	if cfg.CORS != nil {
+		server.CORSOrigins = cfg.CORS.AllowOrigins
+	}
+
+	return server
+}
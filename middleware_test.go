@@ -0,0 +1,135 @@
+package xrpc
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type middlewareInput struct {
+	Name string `json:"name"`
+}
+
+func newMiddlewareTestApp(cfg XRPCConfig) IApp {
+	cfg.Name, cfg.AutoGenTRPCSpec = "Middleware", false
+
+	app := NewXRPC(cfg)
+	app.Router("greeting",
+		NewProcedure[middlewareInput, string]("hello").Query(func(c Context[middlewareInput, string]) error {
+			return c.Json(http.StatusOK, "hello")
+		}),
+		NewProcedure[middlewareInput, string]("slow").Query(func(c Context[middlewareInput, string]) error {
+			<-c.Context().Done()
+			return c.Context().Err()
+		}),
+	)
+
+	return app
+}
+
+func TestCORS(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		cors        CORSConfig
+		method      string
+		origin      string
+		status      int
+		allowOrigin string
+		credentials string
+		maxAge      string
+	}{
+		{
+			name:   "preflight",
+			cors:   CORSConfig{AllowOrigins: []string{"https://app.example"}},
+			method: http.MethodOptions, origin: "https://app.example",
+			status: http.StatusNoContent, allowOrigin: "https://app.example", maxAge: "600",
+		},
+		{
+			name:   "preflight from another origin",
+			cors:   CORSConfig{AllowOrigins: []string{"https://app.example"}},
+			method: http.MethodOptions, origin: "https://evil.example",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "any origin",
+			cors:   CORSConfig{},
+			method: http.MethodGet, origin: "https://app.example",
+			status: http.StatusOK, allowOrigin: "*",
+		},
+		{
+			name:   "credentials",
+			cors:   CORSConfig{AllowOrigins: []string{"https://app.example"}, AllowCredentials: true},
+			method: http.MethodGet, origin: "https://app.example",
+			status: http.StatusOK, allowOrigin: "https://app.example", credentials: "true",
+		},
+		{
+			name:   "credentials with any origin",
+			cors:   CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true},
+			method: http.MethodGet, origin: "https://evil.example",
+			status: http.StatusOK, allowOrigin: "*",
+		},
+	} {
+		var logs bytes.Buffer
+		app := newMiddlewareTestApp(XRPCConfig{CORS: &tc.cors, Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+
+		req := httptest.NewRequest(tc.method, "/greeting/hello/", nil)
+		req.Header.Set("Origin", tc.origin)
+		if tc.method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+		rec := httptest.NewRecorder()
+		app.Server().ServeHTTP(rec, req)
+
+		header := rec.Header()
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.status)
+		}
+		if got := header.Get("Access-Control-Allow-Origin"); got != tc.allowOrigin {
+			t.Errorf("%s: Access-Control-Allow-Origin %q, want %q", tc.name, got, tc.allowOrigin)
+		}
+		if got := header.Get("Access-Control-Allow-Credentials"); got != tc.credentials {
+			t.Errorf("%s: Access-Control-Allow-Credentials %q, want %q", tc.name, got, tc.credentials)
+		}
+		if got := header.Get("Access-Control-Max-Age"); got != tc.maxAge {
+			t.Errorf("%s: Access-Control-Max-Age %q, want %q", tc.name, got, tc.maxAge)
+		}
+
+		misconfigured := tc.cors.AllowCredentials && tc.credentials == ""
+		if logged := strings.Contains(logs.String(), "disabling credentials"); logged != misconfigured {
+			t.Errorf("%s: logged the misconfiguration %t, want %t", tc.name, logged, misconfigured)
+		}
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	app := newMiddlewareTestApp(XRPCConfig{MaxBodySize: 16})
+
+	req := httptest.NewRequest(http.MethodGet, "/greeting/hello/", strings.NewReader(`{"name":"a long name"}`))
+	rec := httptest.NewRecorder()
+	app.Server().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want 413: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	app := newMiddlewareTestApp(XRPCConfig{Timeout: 20 * time.Millisecond})
+
+	rec := httptest.NewRecorder()
+	app.Server().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/greeting/slow/", nil))
+
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "request timed out") {
+		t.Errorf("status %d, want 503: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	app.Server().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/greeting/hello/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("fast request: status %d, want 200: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,98 @@
+package xrpc
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// Policy decides whether the principal may call a procedure. A policy
+// requiring roles or scopes rejects unauthenticated calls with 401 and
+// authenticated ones it denies with 403.
+type Policy struct {
+	// Name identifies the policy in the spec and in error responses.
+	Name string
+	// Roles are met by a principal having any one of them.
+	Roles []string
+	// Scopes are met by a principal having all of them.
+	Scopes []string
+	// Public marks a procedure as intentionally callable by anyone.
+	Public bool
+
+	allow func(principal *Principal, input any) bool
+}
+
+// RequireRoles allows principals having any of the roles.
+func RequireRoles(roles ...string) Policy {
+	return Policy{Name: "roles", Roles: roles}
+}
+
+// RequireScopes allows principals having all of the scopes.
+func RequireScopes(scopes ...string) Policy {
+	return Policy{Name: "scopes", Scopes: scopes}
+}
+
+// Public allows every caller, documenting that the procedure is meant to be
+// public, e.g. when XRPCConfig.RequirePolicies is set.
+func Public() Policy {
+	return Policy{Name: "public", Public: true}
+}
+
+// Check allows calls for which fn returns true, given the principal, which
+// is nil for unauthenticated calls, and the input, which is bound for
+// procedures with an Input validator. It denies calls to procedures whose
+// input is not a T.
+func Check[T any](name string, fn func(principal *Principal, input T) bool) Policy {
+	return Policy{Name: name, allow: func(principal *Principal, input any) bool {
+		typed, ok := input.(T)
+		return ok && fn(principal, typed)
+	}}
+}
+
+var denyAll = Policy{Name: "deny", allow: func(*Principal, any) bool { return false }}
+
+func (p Policy) authorize(principal *Principal, input any) error {
+	if p.Public {
+		return nil
+	}
+
+	if principal == nil && (len(p.Roles) > 0 || len(p.Scopes) > 0) {
+		return &XRPCError{Code: http.StatusUnauthorized, Detail: "authentication required"}
+	}
+
+	allowed := (len(p.Roles) == 0 || lo.SomeBy(p.Roles, principal.HasRole)) &&
+		lo.EveryBy(p.Scopes, principal.HasScope) &&
+		(p.allow == nil || p.allow(principal, input))
+	if !allowed {
+		return &XRPCError{Code: http.StatusForbidden, Detail: fmt.Sprintf("forbidden by policy %s", p.Name)}
+	}
+
+	return nil
+}
+
+func (p Policy) spec() XRPCSpecPolicy {
+	return XRPCSpecPolicy{Name: p.Name, Roles: p.Roles, Scopes: p.Scopes, Public: p.Public}
+}
+
+// PolicyTable assigns policies to procedures by path, so the authorization
+// of a whole server can be reviewed in one place. A path ending in * matches
+// every procedure under the prefix, e.g. "/admin/*".
+type PolicyTable map[string][]Policy
+
+func (t PolicyTable) match(path string) []Policy {
+	patterns := lo.Keys(t)
+	sort.Strings(patterns)
+
+	policies := []Policy{}
+	for _, pattern := range patterns {
+		prefix, wildcard := strings.CutSuffix(pattern, "*")
+		if (wildcard && strings.HasPrefix(path, JoinPath(prefix))) || JoinPath(pattern) == path {
+			policies = append(policies, t[pattern]...)
+		}
+	}
+
+	return policies
+}
@@ -0,0 +1,109 @@
+package xrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/struckchure/xrpc/validation"
+)
+
+type policyInput struct {
+	Owner string `json:"owner"`
+}
+
+func TestPolicies(t *testing.T) {
+	auth := NewAPIKeyAuth(APIKeyConfig{Store: StaticAPIKeys{
+		"admin":  {Subject: "admin", Roles: []string{"admin"}, Scopes: []string{"posts:write"}},
+		"reader": {Subject: "reader", Roles: []string{"reader"}},
+	}})
+	ok := func(c Context[policyInput, string]) error { return c.Json(http.StatusOK, "ok") }
+
+	app := NewXRPC(XRPCConfig{
+		Name:            "Policies",
+		AutoGenTRPCSpec: false,
+		Policies: PolicyTable{
+			"admin/*":     {RequireRoles("admin")},
+			"post/delete": {RequireScopes("posts:write")},
+		},
+		RequirePolicies: true,
+	})
+	app.Router("post",
+		NewProcedure[policyInput, string]("list").Authorize(Public()).Query(ok),
+		NewProcedure[policyInput, string]("draft").Query(ok),
+		NewProcedure[policyInput, string]("feed").Authorize(RequireRoles("reader")).Query(ok),
+		NewProcedure[policyInput, string]("mine").
+			Auth(auth).
+			Authorize(Check("owner", func(principal *Principal, input policyInput) bool { return input.Owner == principal.Subject })).
+			Input(validation.NewValidator().Field("Owner", validation.String())).
+			Mutation(ok),
+		NewProcedure[policyInput, string]("delete").Auth(auth).Mutation(ok),
+	)
+	app.Router("admin", NewProcedure[policyInput, string]("stats").Auth(auth).Query(ok))
+	app.Router("administrator", NewProcedure[policyInput, string]("stats").Auth(auth).Query(ok))
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		apiKey string
+		body   string
+		status int
+		detail string
+	}{
+		{name: "public procedure", method: http.MethodGet, path: "/post/list/", status: http.StatusOK},
+		{name: "procedure without policies", method: http.MethodGet, path: "/post/draft/", status: http.StatusForbidden, detail: "forbidden by policy deny"},
+		{name: "role without principal", method: http.MethodGet, path: "/post/feed/", status: http.StatusUnauthorized, detail: "authentication required"},
+		{name: "check passing", method: http.MethodPost, path: "/post/mine/", apiKey: "reader", body: `{"owner":"reader"}`, status: http.StatusOK},
+		{name: "check failing", method: http.MethodPost, path: "/post/mine/", apiKey: "reader", body: `{"owner":"admin"}`, status: http.StatusForbidden, detail: "forbidden by policy owner"},
+		{name: "auth without credentials", method: http.MethodPost, path: "/post/mine/", body: `{"owner":"reader"}`, status: http.StatusUnauthorized},
+		{name: "table scope missing", method: http.MethodPost, path: "/post/delete/", apiKey: "reader", status: http.StatusForbidden, detail: "forbidden by policy scopes"},
+		{name: "table scope present", method: http.MethodPost, path: "/post/delete/", apiKey: "admin", status: http.StatusOK},
+		{name: "table wildcard role missing", method: http.MethodGet, path: "/admin/stats/", apiKey: "reader", status: http.StatusForbidden, detail: "forbidden by policy roles"},
+		{name: "table wildcard role present", method: http.MethodGet, path: "/admin/stats/", apiKey: "admin", status: http.StatusOK},
+		{name: "table wildcard other prefix", method: http.MethodGet, path: "/administrator/stats/", apiKey: "reader", status: http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if tc.apiKey != "" {
+			req.Header.Set("X-API-Key", tc.apiKey)
+		}
+		rec := httptest.NewRecorder()
+		app.Server().ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.status, rec.Body)
+		}
+		if tc.detail != "" && !strings.Contains(rec.Body.String(), tc.detail) {
+			t.Errorf("%s: body %s, want detail %q", tc.name, rec.Body, tc.detail)
+		}
+	}
+}
+
+func TestPolicyTableMatch(t *testing.T) {
+	table := PolicyTable{
+		"post/*":      {RequireRoles("author")},
+		"post/delete": {RequireScopes("posts:write")},
+		"/admin/":     {RequireRoles("admin")},
+	}
+
+	for path, want := range map[string][]string{
+		"/post/list/":      {"roles"},
+		"/post/delete/":    {"roles", "scopes"},
+		"/post/":           {"roles"},
+		"/posts/list/":     nil,
+		"/admin/":          {"roles"},
+		"/admin/stats/":    nil,
+		"/comment/create/": nil,
+	} {
+		got := []string{}
+		for _, policy := range table.match(path) {
+			got = append(got, policy.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: policies %v, want %v", path, got, want)
+		}
+	}
+}
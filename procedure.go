@@ -1,11 +1,14 @@
 package xrpc
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
 	"github.com/struckchure/xrpc/validation"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type ProcedureCallback[T, R any] func(Context[T, R]) error
@@ -13,6 +16,16 @@ type ProcedureCallback[T, R any] func(Context[T, R]) error
 type IProcedure[T, R any] interface {
 	Input(*validation.Validator) IProcedure[T, R]
 	Use(...ProcedureCallback[T, R]) IProcedure[T, R]
+	Describe(string) IProcedure[T, R]
+	Example(input T, output R) IProcedure[T, R]
+	Tags(...string) IProcedure[T, R]
+	Deprecated(reason string, sunset time.Time) IProcedure[T, R]
+	Auth(...Authenticator) IProcedure[T, R]
+	Authorize(...Policy) IProcedure[T, R]
+	RateLimit(RateLimit) IProcedure[T, R]
+	Idempotent(...IdempotencyConfig) IProcedure[T, R]
+	Cache(ttl time.Duration, varyBy ...string) IProcedure[T, R]
+	PublicCache(ttl time.Duration, varyBy ...string) IProcedure[T, R]
 	Query(ProcedureCallback[T, R]) func(string, IApp)
 	Mutation(ProcedureCallback[T, R]) func(string, IApp)
 }
@@ -22,6 +35,14 @@ type Procedure[T, R any] struct {
 	validator   *validation.Validator
 	ctx         Context[T, R]
 	middlewares []ProcedureCallback[T, R]
+	description string
+	tags        []string
+	examples    []XRPCSpecExample
+	deprecation *XRPCSpecDeprecation
+	auth        []XRPCSpecAuth
+	policies    []Policy
+	idempotency *idempotency
+	cache       *cachePolicy
 }
 
 func (p *Procedure[T, R]) Input(v *validation.Validator) IProcedure[T, R] {
@@ -38,27 +59,216 @@ func (p *Procedure[T, R]) Use(middlewares ...ProcedureCallback[T, R]) IProcedure
 	return p
 }
 
-func (p *Procedure[T, R]) handler(c echo.Context, callback ProcedureCallback[T, R]) error {
+func (p *Procedure[T, R]) Describe(description string) IProcedure[T, R] {
+	p.description = description
+
+	return p
+}
+
+// Example records a sample call in the spec. Values are stored as their JSON
+// encoding, so field aliases match what clients send.
+func (p *Procedure[T, R]) Example(input T, output R) IProcedure[T, R] {
+	p.examples = append(p.examples, XRPCSpecExample{Input: jsonValue(input), Output: jsonValue(output)})
+
+	return p
+}
+
+func (p *Procedure[T, R]) Tags(tags ...string) IProcedure[T, R] {
+	p.tags = append(p.tags, tags...)
+
+	return p
+}
+
+// Deprecated marks the procedure in the spec and generated clients, and adds
+// Deprecation and, unless sunset is zero, Sunset headers to its responses.
+func (p *Procedure[T, R]) Deprecated(reason string, sunset time.Time) IProcedure[T, R] {
+	p.deprecation = &XRPCSpecDeprecation{Reason: reason}
+	if !sunset.IsZero() {
+		p.deprecation.Sunset = &sunset
+	}
+
+	return p
+}
+
+// Auth requires callers to authenticate with any of the authenticators, in
+// addition to the requirements set with IApp.Auth, and publishes the
+// requirement in the spec.
+func (p *Procedure[T, R]) Auth(authenticators ...Authenticator) IProcedure[T, R] {
+	p.middlewares = append(p.middlewares, Authenticate[T, R](authenticators...))
+	p.auth = append(p.auth, specAuth(authenticators))
+
+	return p
+}
+
+// Authorize requires calls to be allowed by every policy, checked once the
+// input is validated.
+func (p *Procedure[T, R]) Authorize(policies ...Policy) IProcedure[T, R] {
+	p.policies = append(p.policies, policies...)
+
+	return p
+}
+
+// RateLimit limits calls to the procedure, see RateLimiter. Use it after
+// Auth to limit by principal.
+func (p *Procedure[T, R]) RateLimit(limit RateLimit) IProcedure[T, R] {
+	p.middlewares = append(p.middlewares, RateLimiter[T, R](limit))
+
+	return p
+}
+
+// Idempotent makes a mutation replay its first response to calls repeating
+// its Idempotency-Key, so clients can retry it safely. Calls are rejected
+// while another with the same key is in flight. Queries are unaffected.
+func (p *Procedure[T, R]) Idempotent(cfg ...IdempotencyConfig) IProcedure[T, R] {
+	p.idempotency = newIdempotency(lo.FirstOr(cfg, IdempotencyConfig{}))
+
+	return p
+}
+
+// Cache reuses the responses of a query for ttl, per input, principal and
+// varyBy request headers, and answers clients already holding them, per
+// If-None-Match, with a 304. Call Context.Invalidate from mutations to drop
+// stale responses. Only queries are cached. Responses are marked private, so
+// only the client keeps them.
+func (p *Procedure[T, R]) Cache(ttl time.Duration, varyBy ...string) IProcedure[T, R] {
+	p.cache = &cachePolicy{ttl: ttl, varyBy: varyBy}
+
+	return p
+}
+
+// PublicCache is like Cache, but lets shared caches such as CDNs keep the
+// responses too. Requests with an Authorization header or cookies still get
+// private responses.
+func (p *Procedure[T, R]) PublicCache(ttl time.Duration, varyBy ...string) IProcedure[T, R] {
+	p.cache = &cachePolicy{ttl: ttl, varyBy: varyBy, public: true}
+
+	return p
+}
+
+// enforcedPolicies combines the procedure's policies with those of the
+// app's policy table, denying all calls when policies are required but none
+// apply.
+func (p *Procedure[T, R]) enforcedPolicies(path string, app IApp) []Policy {
+	policies := append(append([]Policy{}, p.policies...), app.Ctx().policyTable.match(path)...)
+
+	if app.Ctx().requirePolicies && len(policies) == 0 && len(p.ctx.rootAuth) == 0 && len(p.auth) == 0 {
+		app.Logger().Warn("procedure has no auth requirement or policy, denying all calls", "path", path)
+		policies = []Policy{denyAll}
+	}
+
+	return policies
+}
+
+func (p *Procedure[T, R]) specProcedure(path string, procedureType XRPCSpecProcedureType, policies []Policy) XRPCSpecProcedure {
+	return XRPCSpecProcedure{
+		Path:        path,
+		Type:        procedureType,
+		Input:       createTypeDescriptor[T](),
+		Output:      createTypeDescriptor[R](),
+		Description: p.description,
+		Tags:        p.tags,
+		Examples:    p.examples,
+		Deprecation: p.deprecation,
+		Auth:        append(append([]XRPCSpecAuth{}, p.ctx.rootAuth...), p.auth...),
+		Policies:    lo.Map(policies, func(policy Policy, _ int) XRPCSpecPolicy { return policy.spec() }),
+		Idempotent:  procedureType == XRPCSpecProcedureTypeMutation && p.idempotency != nil,
+		Cache:       lo.Ternary(procedureType == XRPCSpecProcedureTypeQuery, p.cache.spec(), nil),
+	}
+}
+
+func (p *Procedure[T, R]) handler(
+	c echo.Context, callback ProcedureCallback[T, R], policies []Policy, idempotency *idempotency, cache *cachePolicy,
+) error {
 	var input T
 
+	if p.deprecation != nil {
+		c.Response().Header().Set("Deprecation", "true")
+		if p.deprecation.Sunset != nil {
+			c.Response().Header().Set("Sunset", p.deprecation.Sunset.UTC().Format(http.TimeFormat))
+		}
+	}
+
+	var body []byte
+	if idempotency != nil {
+		var err error
+		body, err = idempotency.readBody(c)
+		if err, ok := err.(*XRPCError); ok {
+			return errorJSON(c, err.Code, err.Detail)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
 	if p.validator != nil {
-		if err := c.Bind(&input); err != nil {
-			return c.JSON(http.StatusBadRequest, echo.Map{"detail": err})
+		var detail any
+		traceStep(p.ctx.tracer, c, "validation", func() error {
+			if err := c.Bind(&input); err != nil {
+				detail = err
+				return err
+			}
+
+			if errs := p.validator.Validate(input); errs != nil {
+				detail = errs
+				return errors.New("input is invalid")
+			}
+
+			return nil
+		})
+		if err, ok := detail.(error); ok && errors.Is(err, echo.ErrStatusRequestEntityTooLarge) {
+			return err
+		}
+		if detail != nil {
+			c.Set(validationErrorKey, detail)
+			return errorJSON(c, http.StatusBadRequest, detail)
+		}
+	}
+
+	if len(policies) > 0 {
+		principal, _ := c.Get(principalKey).(*Principal)
+		err := traceStep(p.ctx.tracer, c, "authorization", func() error {
+			for _, policy := range policies {
+				if err := policy.authorize(principal, input); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err, ok := err.(*XRPCError); ok {
+			return errorJSON(c, err.Code, err.Detail)
 		}
+	}
 
-		if err := p.validator.Validate(input); err != nil {
-			return c.JSON(http.StatusBadRequest, echo.Map{"detail": err})
+	if idempotency != nil {
+		replayed, err := idempotency.begin(c, body)
+		if err, ok := err.(*XRPCError); ok {
+			return errorJSON(c, err.Code, err.Detail)
+		}
+		if replayed || err != nil {
+			return err
 		}
 	}
 
-	p.ctx.ec = c
-	p.ctx.Input = input
+	// Each request gets its own copy of the Context, as requests are served
+	// concurrently.
+	ctx := p.ctx
+	ctx.ec = c
+	ctx.Input = input
 
-	err := callback(p.ctx)
+	run := func() error {
+		return traceStep(p.ctx.tracer, c, "handler", func() error { return callback(ctx) })
+	}
+
+	var err error
+	if cache != nil {
+		err = cache.serve(c, p.ctx.cacheStore, run)
+	} else {
+		err = run()
+	}
 	if err != nil {
 		switch err := err.(type) {
 		case *XRPCError:
-			return c.JSON(err.Code, echo.Map{"detail": err.Detail})
+			return errorJSON(c, err.Code, err.Detail)
 		}
 	}
 	return err
@@ -67,21 +277,22 @@ func (p *Procedure[T, R]) handler(c echo.Context, callback ProcedureCallback[T,
 func (p *Procedure[T, R]) loadMiddlewares(app IApp) []echo.MiddlewareFunc {
 	var middlewareFuncs []echo.MiddlewareFunc = []echo.MiddlewareFunc{}
 
-	for _, middleware := range app.Ctx().rootMiddlewares {
+	for i, middleware := range app.Ctx().rootMiddlewares {
 		middlewareFunc := func(next echo.HandlerFunc) echo.HandlerFunc {
 			return func(c echo.Context) error {
-				app.Ctx(func(innerCtx Context[any, any]) Context[any, any] {
-					innerCtx.ec = c
-					return innerCtx
-				})
+				ctx := app.Ctx()
+				ctx.ec = c
 
-				err := middleware(app.Ctx())
+				err := traceStep(p.ctx.tracer, c, "middleware", func() error { return middleware(ctx) },
+					attribute.String("xrpc.middleware.scope", "root"),
+					attribute.Int("xrpc.middleware.index", i),
+				)
 				if err != nil {
 					switch err := err.(type) {
 					case *XRPCError:
-						return c.JSON(err.Code, echo.Map{"detail": err.Detail})
+						return errorJSON(c, err.Code, err.Detail)
 					default:
-						return c.JSON(http.StatusInternalServerError, echo.Map{"detail": err})
+						return errorJSON(c, http.StatusInternalServerError, err)
 					}
 				}
 
@@ -91,18 +302,22 @@ func (p *Procedure[T, R]) loadMiddlewares(app IApp) []echo.MiddlewareFunc {
 		middlewareFuncs = append(middlewareFuncs, middlewareFunc)
 	}
 
-	for _, middleware := range p.middlewares {
+	for i, middleware := range p.middlewares {
 		middlewareFunc := func(next echo.HandlerFunc) echo.HandlerFunc {
 			return func(c echo.Context) error {
-				p.ctx.ec = c
+				ctx := p.ctx
+				ctx.ec = c
 
-				err := middleware(p.ctx)
+				err := traceStep(p.ctx.tracer, c, "middleware", func() error { return middleware(ctx) },
+					attribute.String("xrpc.middleware.scope", "procedure"),
+					attribute.Int("xrpc.middleware.index", i),
+				)
 				if err != nil {
 					switch err := err.(type) {
 					case *XRPCError:
-						return c.JSON(err.Code, echo.Map{"detail": err.Detail})
+						return errorJSON(c, err.Code, err.Detail)
 					default:
-						return c.JSON(http.StatusInternalServerError, echo.Map{"detail": err})
+						return errorJSON(c, http.StatusInternalServerError, err)
 					}
 				}
 				return next(c)
@@ -119,26 +334,30 @@ func (p *Procedure[T, R]) Query(callback ProcedureCallback[T, R]) func(string, I
 		p.ctx.Injector = app.Ctx().Injector
 		p.ctx.sharedValue = app.Ctx().sharedValue
 		p.ctx.rootMiddlewares = app.Ctx().rootMiddlewares
+		p.ctx.rootAuth = app.Ctx().rootAuth
+		p.ctx.logger = app.Ctx().logger
+		p.ctx.tracer = app.Ctx().tracer
+		p.ctx.cacheStore = app.Ctx().cacheStore
+
+		var policies []Policy
 
 		path = JoinPath(path, p.name)
 		path = app.Get(Route{
-			path:        path,
-			handler:     func(c echo.Context) error { return p.handler(c, callback) },
-			middlewares: p.loadMiddlewares(app),
+			path:          path,
+			handler:       func(c echo.Context) error { return p.handler(c, callback, policies, nil, p.cache) },
+			middlewares:   p.loadMiddlewares(app),
+			procedureType: XRPCSpecProcedureTypeQuery,
 		})
+		policies = p.enforcedPolicies(path, app)
 
+		specProcedure := p.specProcedure(path, XRPCSpecProcedureTypeQuery, policies)
 		app.Spec(func(spec TRPCSpec) TRPCSpec {
-			spec.Procedures = append(spec.Procedures, XRPCSpecProcedure{
-				Path:   path,
-				Type:   XRPCSpecProcedureTypeQuery,
-				Input:  createTypeDescriptor[T](),
-				Output: createTypeDescriptor[R](),
-			})
+			spec.Procedures = append(spec.Procedures, specProcedure)
 
 			return spec
 		})
 
-		fmt.Printf("[xRPC] [%s] %s\n", XRPCSpecProcedureTypeQuery, path)
+		app.Logger().Info("procedure registered", "type", XRPCSpecProcedureTypeQuery, "path", path, "public", specProcedure.IsPublic())
 	}
 }
 
@@ -147,26 +366,30 @@ func (p *Procedure[T, R]) Mutation(callback ProcedureCallback[T, R]) func(string
 		p.ctx.Injector = app.Ctx().Injector
 		p.ctx.sharedValue = app.Ctx().sharedValue
 		p.ctx.rootMiddlewares = app.Ctx().rootMiddlewares
+		p.ctx.rootAuth = app.Ctx().rootAuth
+		p.ctx.logger = app.Ctx().logger
+		p.ctx.tracer = app.Ctx().tracer
+		p.ctx.cacheStore = app.Ctx().cacheStore
+
+		var policies []Policy
 
 		path = JoinPath(path, p.name)
 		path = app.Post(Route{
-			path:        path,
-			handler:     func(c echo.Context) error { return p.handler(c, callback) },
-			middlewares: p.loadMiddlewares(app),
+			path:          path,
+			handler:       func(c echo.Context) error { return p.handler(c, callback, policies, p.idempotency, nil) },
+			middlewares:   p.loadMiddlewares(app),
+			procedureType: XRPCSpecProcedureTypeMutation,
 		})
+		policies = p.enforcedPolicies(path, app)
 
+		specProcedure := p.specProcedure(path, XRPCSpecProcedureTypeMutation, policies)
 		app.Spec(func(spec TRPCSpec) TRPCSpec {
-			spec.Procedures = append(spec.Procedures, XRPCSpecProcedure{
-				Path:   path,
-				Type:   XRPCSpecProcedureTypeMutation,
-				Input:  createTypeDescriptor[T](),
-				Output: createTypeDescriptor[R](),
-			})
+			spec.Procedures = append(spec.Procedures, specProcedure)
 
 			return spec
 		})
 
-		fmt.Printf("[xRPC] [%s] %s\n", XRPCSpecProcedureTypeMutation, path)
+		app.Logger().Info("procedure registered", "type", XRPCSpecProcedureTypeMutation, "path", path, "public", specProcedure.IsPublic())
 	}
 }
 
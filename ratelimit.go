@@ -0,0 +1,229 @@
+package xrpc
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimitKey identifies the caller a request is limited as. principal is
+// nil for unauthenticated requests.
+type RateLimitKey func(r *http.Request, principal *Principal) string
+
+// RateLimitByIP limits requests by the client address. Behind a proxy, use
+// a key reading the header it sets instead.
+func RateLimitByIP(r *http.Request, _ *Principal) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// RateLimitByCaller limits authenticated requests by principal and others by
+// client address.
+func RateLimitByCaller(r *http.Request, principal *Principal) string {
+	if principal != nil {
+		return string(principal.Scheme) + ":" + principal.Subject
+	}
+
+	return "ip:" + RateLimitByIP(r, principal)
+}
+
+type RateLimit struct {
+	// Rate requests are allowed every Per, e.g. 10 per second, with bursts
+	// of up to Burst requests, which defaults to Rate.
+	Rate  int
+	Per   time.Duration
+	Burst int
+	// MaxConcurrent limits the requests in flight per key, in this process.
+	MaxConcurrent int
+	// Key defaults to RateLimitByCaller.
+	Key RateLimitKey
+	// Name shares buckets across the procedures limited under it. By
+	// default, each procedure has its own.
+	Name string
+	// Store holds the token buckets, defaults to a MemoryRateLimitStore.
+	Store RateLimitStore
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the wait for the next token, when not allowed.
+	RetryAfter time.Duration
+	// Reset is the wait until the bucket is full again.
+	Reset time.Duration
+}
+
+// RateLimitStore keeps the token buckets of rate limited procedures. Take
+// runs for every request, so concurrent requests for one key must not both
+// spend the last token.
+type RateLimitStore interface {
+	// Take takes a token from the bucket at key, holding up to burst tokens
+	// and refilled with one token every interval.
+	Take(ctx context.Context, key string, burst int, interval time.Duration) (RateLimitResult, error)
+}
+
+// MemoryRateLimitStore keeps token buckets in memory, dropping those that
+// have refilled.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+type tokenBucket struct {
+	tokens   float64
+	burst    int
+	interval time.Duration
+	updated  time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*tokenBucket{}, swept: time.Now()}
+}
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, burst int, interval time.Duration) (RateLimitResult, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.swept) > time.Minute {
+		for key, bucket := range s.buckets {
+			if bucket.refill(now) >= float64(bucket.burst) {
+				delete(s.buckets, key)
+			}
+		}
+		s.swept = now
+	}
+
+	bucket, exists := s.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(burst), updated: now}
+		s.buckets[key] = bucket
+	}
+	bucket.burst, bucket.interval = burst, interval
+	bucket.tokens, bucket.updated = bucket.refill(now), now
+
+	result := RateLimitResult{}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - bucket.tokens) * float64(interval))
+	}
+	result.Remaining = int(bucket.tokens)
+	result.Reset = time.Duration((float64(burst) - bucket.tokens) * float64(interval))
+
+	return result, nil
+}
+
+func (b *tokenBucket) refill(now time.Time) float64 {
+	return math.Min(float64(b.burst), b.tokens+float64(now.Sub(b.updated))/float64(b.interval))
+}
+
+type rateLimiter struct {
+	cfg      RateLimit
+	interval time.Duration
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// RateLimiter is a middleware limiting requests with a token bucket per
+// caller, and their concurrency. Rejected requests get a 429 with a
+// Retry-After header, and limited ones X-RateLimit-Limit, -Remaining and
+// -Reset headers. Use it after auth middlewares to limit by principal.
+func RateLimiter[T, R any](cfg RateLimit) ProcedureCallback[T, R] {
+	limiter := newRateLimiter(cfg)
+
+	return func(c Context[T, R]) error {
+		return limiter.limit(c.ec, c.Logger())
+	}
+}
+
+func newRateLimiter(cfg RateLimit) *rateLimiter {
+	if cfg.Burst <= 0 {
+		cfg.Burst = cfg.Rate
+	}
+	if cfg.Per <= 0 {
+		cfg.Per = time.Second
+	}
+	if cfg.Key == nil {
+		cfg.Key = RateLimitByCaller
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryRateLimitStore()
+	}
+
+	limiter := &rateLimiter{cfg: cfg, inFlight: map[string]int{}}
+	if cfg.Rate > 0 {
+		limiter.interval = cfg.Per / time.Duration(cfg.Rate)
+	}
+
+	return limiter
+}
+
+func (l *rateLimiter) limit(c echo.Context, logger *slog.Logger) error {
+	principal, _ := c.Get(principalKey).(*Principal)
+	key := l.cfg.Name
+	if key == "" {
+		key = c.Path()
+	}
+	key += ":" + l.cfg.Key(c.Request(), principal)
+
+	header := c.Response().Header()
+
+	if l.interval > 0 {
+		result, err := l.cfg.Store.Take(c.Request().Context(), key, l.cfg.Burst, l.interval)
+		if err != nil {
+			// Fail open, an unavailable store should not take the API down.
+			logger.Error("failed to take rate limit token", "key", key, "error", err)
+		} else {
+			header.Set("X-RateLimit-Limit", strconv.Itoa(l.cfg.Burst))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				return &XRPCError{Code: http.StatusTooManyRequests, Detail: "rate limit exceeded"}
+			}
+		}
+	}
+
+	if l.cfg.MaxConcurrent > 0 {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if l.inFlight[key] >= l.cfg.MaxConcurrent {
+			header.Set("Retry-After", "1")
+			return &XRPCError{Code: http.StatusTooManyRequests, Detail: "too many concurrent requests"}
+		}
+
+		l.inFlight[key]++
+		onDone(c, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			if l.inFlight[key]--; l.inFlight[key] <= 0 {
+				delete(l.inFlight, key)
+			}
+		})
+	}
+
+	return nil
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
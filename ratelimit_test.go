@@ -0,0 +1,127 @@
+package xrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newRateLimitTestApp(limit RateLimit, handler func()) IApp {
+	app := NewXRPC(XRPCConfig{Name: "Rate Limit", AutoGenTRPCSpec: false})
+	app.Router("post", NewProcedure[struct{}, string]("list").RateLimit(limit).Query(func(c Context[struct{}, string]) error {
+		if handler != nil {
+			handler()
+		}
+		return c.Json(http.StatusOK, "ok")
+	}))
+
+	return app
+}
+
+func rateLimitTestRequest(app IApp, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/post/list/", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	app.Server().ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	app := newRateLimitTestApp(RateLimit{Rate: 2, Per: time.Minute}, nil)
+
+	for i, want := range []struct {
+		addr       string
+		status     int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{addr: "10.0.0.1:1000", status: http.StatusOK, remaining: "1", reset: "30"},
+		{addr: "10.0.0.1:1001", status: http.StatusOK, remaining: "0", reset: "60"},
+		{addr: "10.0.0.1:1002", status: http.StatusTooManyRequests, remaining: "0", reset: "60", retryAfter: "30"},
+		{addr: "10.0.0.2:1000", status: http.StatusOK, remaining: "1", reset: "30"},
+	} {
+		rec := rateLimitTestRequest(app, want.addr)
+		header := rec.Header()
+
+		if rec.Code != want.status {
+			t.Errorf("request %d: status %d, want %d: %s", i, rec.Code, want.status, rec.Body)
+		}
+		for name, value := range map[string]string{
+			"X-RateLimit-Limit":     "2",
+			"X-RateLimit-Remaining": want.remaining,
+			"X-RateLimit-Reset":     want.reset,
+			"Retry-After":           want.retryAfter,
+		} {
+			if header.Get(name) != value {
+				t.Errorf("request %d: %s %q, want %q", i, name, header.Get(name), value)
+			}
+		}
+	}
+}
+
+func TestRateLimitConcurrentRequests(t *testing.T) {
+	app := newRateLimitTestApp(RateLimit{Rate: 50, Per: time.Hour}, nil)
+
+	var allowed, limited atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 20 {
+				switch rec := rateLimitTestRequest(app, "10.0.0.1:1000"); rec.Code {
+				case http.StatusOK:
+					allowed.Add(1)
+				case http.StatusTooManyRequests:
+					limited.Add(1)
+				default:
+					t.Errorf("status %d: %s", rec.Code, rec.Body)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed.Load() != 50 || limited.Load() != 150 {
+		t.Errorf("%d allowed and %d limited, want 50 and 150", allowed.Load(), limited.Load())
+	}
+}
+
+func TestRateLimitMaxConcurrent(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	app := newRateLimitTestApp(RateLimit{MaxConcurrent: 2}, func() {
+		started <- struct{}{}
+		<-release
+	})
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec := rateLimitTestRequest(app, "10.0.0.1:1000"); rec.Code != http.StatusOK {
+				t.Errorf("request in flight: status %d: %s", rec.Code, rec.Body)
+			}
+		}()
+		<-started
+	}
+
+	rec := rateLimitTestRequest(app, "10.0.0.1:1000")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("third request: status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	close(release)
+	wg.Wait()
+
+	go func() { <-started }()
+	if rec := rateLimitTestRequest(app, "10.0.0.1:1000"); rec.Code != http.StatusOK {
+		t.Errorf("request after the others completed: status %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -1,5 +1,7 @@
 package xrpc
 
+import "time"
+
 type XRPCSpecProcedureType string
 
 const (
@@ -8,14 +10,101 @@ const (
 )
 
 type XRPCSpecProcedure struct {
-	Path   string                `yaml:"path"`
-	Type   XRPCSpecProcedureType `yaml:"type"`
-	Input  TypeDescriptor        `yaml:"input"`
-	Output TypeDescriptor        `yaml:"output"`
+	Path        string                `json:"path" yaml:"path"`
+	Type        XRPCSpecProcedureType `json:"type" yaml:"type"`
+	Input       TypeDescriptor        `json:"input" yaml:"input"`
+	Output      TypeDescriptor        `json:"output" yaml:"output"`
+	Description string                `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Examples    []XRPCSpecExample     `json:"examples,omitempty" yaml:"examples,omitempty"`
+	Deprecation *XRPCSpecDeprecation  `json:"deprecation,omitempty" yaml:"deprecation,omitempty"`
+	// Auth lists the requirements a caller must meet, all of them.
+	Auth []XRPCSpecAuth `json:"auth,omitempty" yaml:"auth,omitempty"`
+	// Policies authorize authenticated callers, all of them.
+	Policies []XRPCSpecPolicy `json:"policies,omitempty" yaml:"policies,omitempty"`
+	// Idempotent mutations replay their response to calls repeating an
+	// Idempotency-Key header, so clients can retry them.
+	Idempotent bool `json:"idempotent,omitempty" yaml:"idempotent,omitempty"`
+	// Cache describes how long query responses may be reused.
+	Cache *XRPCSpecCache `json:"cache,omitempty" yaml:"cache,omitempty"`
+}
+
+// IsPublic reports whether anyone may call the procedure: it has no auth
+// requirement and no policy other than Public.
+func (p XRPCSpecProcedure) IsPublic() bool {
+	for _, policy := range p.Policies {
+		if !policy.Public {
+			return false
+		}
+	}
+
+	return len(p.Auth) == 0
+}
+
+// XRPCSpecExample holds an input and the output it produces, as the JSON
+// values sent over the wire.
+type XRPCSpecExample struct {
+	Input  any `json:"input" yaml:"input"`
+	Output any `json:"output" yaml:"output"`
+}
+
+type XRPCSpecCache struct {
+	// MaxAge is the number of seconds a response may be reused.
+	MaxAge int `json:"max_age" yaml:"max_age"`
+	// VaryBy lists the request headers responses differ by.
+	VaryBy []string `json:"vary_by,omitempty" yaml:"vary_by,omitempty"`
+}
+
+// XRPCSpecServer describes the server settings that affect clients.
+type XRPCSpecServer struct {
+	// MaxBodySize is the largest request body accepted, in bytes.
+	MaxBodySize int64 `json:"max_body_size,omitempty" yaml:"max_body_size,omitempty"`
+	// Timeout is the number of seconds requests may take.
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Gzip is set when responses are compressed for clients accepting it.
+	Gzip bool `json:"gzip,omitempty" yaml:"gzip,omitempty"`
+	// CORSOrigins lists the origins browsers may call from, "*" for any.
+	CORSOrigins []string `json:"cors_origins,omitempty" yaml:"cors_origins,omitempty"`
+}
+
+type XRPCSpecDeprecation struct {
+	Reason string     `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Sunset *time.Time `json:"sunset,omitempty" yaml:"sunset,omitempty"`
+}
+
+type XRPCSpecAuthType string
+
+const (
+	XRPCSpecAuthTypeBearer XRPCSpecAuthType = "bearer"
+	XRPCSpecAuthTypeAPIKey XRPCSpecAuthType = "api_key"
+)
+
+// XRPCSpecAuth is an auth requirement, met by any one of its schemes.
+type XRPCSpecAuth struct {
+	Schemes []XRPCSpecAuthScheme `json:"schemes" yaml:"schemes"`
+}
+
+// XRPCSpecAuthScheme describes how credentials are sent: a bearer token in
+// the Authorization header, in the given format, or an API key in Header.
+type XRPCSpecAuthScheme struct {
+	Type   XRPCSpecAuthType `json:"type" yaml:"type"`
+	Format string           `json:"format,omitempty" yaml:"format,omitempty"`
+	Header string           `json:"header,omitempty" yaml:"header,omitempty"`
+}
+
+// XRPCSpecPolicy describes a policy: the roles, any of which is required,
+// the scopes, all of which are required, or, for custom checks, its name.
+type XRPCSpecPolicy struct {
+	Name   string   `json:"name" yaml:"name"`
+	Roles  []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	Public bool     `json:"public,omitempty" yaml:"public,omitempty"`
 }
 
 type TRPCSpec struct {
-	Name       string              `yaml:"name"`
-	ServerUrl  string              `yaml:"server_url"`
-	Procedures []XRPCSpecProcedure `yaml:"procedures"`
+	SpecVersion int                 `json:"spec_version" yaml:"spec_version"`
+	Name        string              `json:"name" yaml:"name"`
+	ServerUrl   string              `json:"server_url" yaml:"server_url"`
+	Server      *XRPCSpecServer     `json:"server,omitempty" yaml:"server,omitempty"`
+	Procedures  []XRPCSpecProcedure `json:"procedures" yaml:"procedures"`
 }
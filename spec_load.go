@@ -0,0 +1,333 @@
+package xrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// SpecVersion is the spec format written by GenerateSpec. Files without a
+// spec_version predate nested struct descriptors and are version 1.
+const SpecVersion = 2
+
+// specBuiltinTypes are the field types that need no declaration in the spec.
+var specBuiltinTypes = []string{
+	"string", "bool", "byte", "rune", "uintptr",
+	"int", "int8", "int16", "int32", "int64",
+	"uint", "uint8", "uint16", "uint32", "uint64",
+	"float32", "float64", "complex64", "complex128",
+	"interface {}", "interface{}", "any", "nil",
+	"time.Time", "time.Duration", "json.RawMessage",
+}
+
+// LoadSpec reads a spec written by GenerateSpec, as YAML or, for .json files,
+// JSON, migrates it to SpecVersion and validates it.
+func LoadSpec(path string) (TRPCSpec, error) {
+	spec := TRPCSpec{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return spec, fmt.Errorf("failed to read spec: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &spec)
+	} else {
+		err = yaml.Unmarshal(data, &spec)
+	}
+	if err != nil {
+		return spec, fmt.Errorf("failed to parse spec %s: %w", path, err)
+	}
+
+	spec, err = MigrateSpec(spec)
+	if err != nil {
+		return spec, fmt.Errorf("failed to migrate spec %s: %w", path, err)
+	}
+
+	err = ValidateSpec(spec)
+	if err != nil {
+		return spec, fmt.Errorf("invalid spec %s:\n%w", path, err)
+	}
+
+	return spec, nil
+}
+
+// MigrateSpec upgrades a spec written by an older version of xRPC.
+func MigrateSpec(spec TRPCSpec) (TRPCSpec, error) {
+	if spec.SpecVersion > SpecVersion {
+		return spec, fmt.Errorf("spec version %d is newer than the supported version %d", spec.SpecVersion, SpecVersion)
+	}
+
+	if spec.SpecVersion < 2 {
+		// Version 1 only described procedure inputs and outputs, so nested
+		// structs are resolved from those where possible.
+		declared := specDeclaredTypes(spec)
+		for i := range spec.Procedures {
+			spec.Procedures[i].Input = migrateDescriptor(spec.Procedures[i].Input, declared)
+			spec.Procedures[i].Output = migrateDescriptor(spec.Procedures[i].Output, declared)
+		}
+	}
+
+	spec.SpecVersion = SpecVersion
+
+	return spec, nil
+}
+
+func migrateDescriptor(descriptor TypeDescriptor, declared map[string]TypeDescriptor) TypeDescriptor {
+	if descriptor.Array != nil {
+		array := migrateDescriptor(*descriptor.Array, declared)
+		descriptor.Array = &array
+	}
+
+	descriptor.Fields = lo.Map(descriptor.Fields, func(field FieldDescriptor, _ int) FieldDescriptor {
+		if field.Struct != nil {
+			return field
+		}
+
+		for _, name := range specTypeNames(field.Type) {
+			if nested, exists := declared[name]; exists && name != descriptor.TypeName {
+				field.Struct = &nested
+			}
+		}
+
+		return field
+	})
+
+	return descriptor
+}
+
+// ValidateSpec reports every structural problem in the spec that would make
+// generated clients fail to compile or call the wrong route.
+func ValidateSpec(spec TRPCSpec) error {
+	errs := []error{}
+	fail := func(format string, args ...any) { errs = append(errs, fmt.Errorf(format, args...)) }
+
+	declared := specDeclaredTypes(spec)
+	paths := map[string]bool{}
+
+	for i, procedure := range spec.Procedures {
+		where := fmt.Sprintf("procedures[%d] %s", i, procedure.Path)
+
+		switch {
+		case procedure.Path == "":
+			fail("procedures[%d]: path is empty", i)
+		case !strings.HasPrefix(procedure.Path, "/"):
+			fail("%s: path must start with /", where)
+		case paths[procedure.Path]:
+			fail("%s: duplicate path", where)
+		}
+		paths[procedure.Path] = true
+
+		if procedure.Type != XRPCSpecProcedureTypeQuery && procedure.Type != XRPCSpecProcedureTypeMutation {
+			fail("%s: type %q must be %s or %s", where, procedure.Type, XRPCSpecProcedureTypeQuery, XRPCSpecProcedureTypeMutation)
+		}
+
+		for j, requirement := range procedure.Auth {
+			if len(requirement.Schemes) == 0 {
+				fail("%s: auth[%d] has no schemes", where, j)
+			}
+			for _, scheme := range requirement.Schemes {
+				switch scheme.Type {
+				case XRPCSpecAuthTypeBearer:
+				case XRPCSpecAuthTypeAPIKey:
+					if scheme.Header == "" {
+						fail("%s: auth[%d] %s scheme has no header", where, j, scheme.Type)
+					}
+				default:
+					fail("%s: auth[%d] type %q must be %s or %s", where, j, scheme.Type, XRPCSpecAuthTypeBearer, XRPCSpecAuthTypeAPIKey)
+				}
+			}
+		}
+
+		for j, policy := range procedure.Policies {
+			if policy.Name == "" {
+				fail("%s: policies[%d] has no name", where, j)
+			}
+		}
+
+		validateDescriptor(where+" input", procedure.Input, declared, fail)
+		validateDescriptor(where+" output", procedure.Output, declared, fail)
+	}
+
+	return errors.Join(append(errs, validateSpecEnums(spec))...)
+}
+
+// validateSpecEnums reports enums sharing a name with a struct, with an enum
+// of other values, or with another registered type, e.g. Status types from
+// two packages, as clients declare a single type per name.
+func validateSpecEnums(spec TRPCSpec) error {
+	errs := []error{}
+	structs := map[string]bool{}
+	enums := map[string][]string{}
+
+	var walk func(descriptor TypeDescriptor)
+	walk = func(descriptor TypeDescriptor) {
+		if descriptor.Array != nil {
+			walk(*descriptor.Array)
+			return
+		}
+
+		if descriptor.TypeName != "" && len(descriptor.Fields) > 0 {
+			structs[descriptor.TypeName] = true
+		}
+
+		for _, field := range descriptor.Fields {
+			if field.Struct != nil {
+				walk(*field.Struct)
+			}
+			if field.Enum == nil {
+				continue
+			}
+
+			name := field.Enum.TypeName
+			if values, exists := enums[name]; exists && !slices.Equal(values, field.Enum.Values) {
+				errs = append(errs, fmt.Errorf("enum %s of field %s.%s has values %v, another enum of that name has %v", name, descriptor.TypeName, field.Name, field.Enum.Values, values))
+			}
+			enums[name] = field.Enum.Values
+		}
+	}
+
+	for _, procedure := range spec.Procedures {
+		walk(procedure.Input)
+		walk(procedure.Output)
+	}
+
+	names := lo.Keys(enums)
+	slices.Sort(names)
+	for _, name := range names {
+		if structs[name] {
+			errs = append(errs, fmt.Errorf("enum %s has the name of a struct", name))
+		}
+	}
+
+	return errors.Join(append(errs, registeredEnumCollisions(names))...)
+}
+
+func validateDescriptor(where string, descriptor TypeDescriptor, declared map[string]TypeDescriptor, fail func(string, ...any)) {
+	if descriptor.Array != nil {
+		validateDescriptor(where+" element", *descriptor.Array, declared, fail)
+		return
+	}
+
+	if descriptor.TypeName == "" {
+		if len(descriptor.Fields) > 0 {
+			fail("%s: anonymous structs are not supported", where)
+		}
+		return
+	}
+
+	if !lo.Contains(specBuiltinTypes, descriptor.TypeName) && !token.IsIdentifier(descriptor.TypeName) {
+		fail("%s: type name %q is not a valid identifier", where, descriptor.TypeName)
+	}
+
+	aliases := map[string]bool{}
+	for _, field := range descriptor.Fields {
+		at := fmt.Sprintf("%s field %s.%s", where, descriptor.TypeName, field.Name)
+
+		if !token.IsIdentifier(field.Name) {
+			fail("%s: %q is not a valid identifier", at, field.Name)
+		}
+
+		switch {
+		case field.Alias == "":
+			fail("%s: alias is empty", at)
+		case aliases[field.Alias]:
+			fail("%s: duplicate alias %q", at, field.Alias)
+		}
+		aliases[field.Alias] = true
+
+		for _, name := range specTypeNames(lo.CoalesceOrEmpty(field.Underlying, field.Type)) {
+			if _, exists := declared[name]; !exists {
+				fail("%s: type %s is not declared in the spec", at, field.Type)
+			}
+		}
+
+		if field.Enum != nil {
+			if !token.IsIdentifier(field.Enum.TypeName) {
+				fail("%s: enum type name %q is not a valid identifier", at, field.Enum.TypeName)
+			}
+			if len(field.Enum.Values) == 0 {
+				fail("%s: enum %s has no values", at, field.Enum.TypeName)
+			}
+		}
+
+		if field.Struct != nil {
+			validateDescriptor(where, *field.Struct, declared, fail)
+		}
+	}
+}
+
+// specDeclaredTypes indexes every named struct described in the spec.
+func specDeclaredTypes(spec TRPCSpec) map[string]TypeDescriptor {
+	declared := map[string]TypeDescriptor{}
+
+	var walk func(descriptor TypeDescriptor)
+	walk = func(descriptor TypeDescriptor) {
+		if descriptor.Array != nil {
+			walk(*descriptor.Array)
+			return
+		}
+
+		if descriptor.TypeName == "" || lo.Contains(specBuiltinTypes, descriptor.TypeName) {
+			return
+		}
+
+		// Self-references are recorded without fields, keep the full one.
+		if existing, exists := declared[descriptor.TypeName]; !exists || len(existing.Fields) < len(descriptor.Fields) {
+			declared[descriptor.TypeName] = descriptor
+		}
+
+		for _, field := range descriptor.Fields {
+			if field.Struct != nil {
+				walk(*field.Struct)
+			}
+			if field.Enum != nil {
+				declared[field.Enum.TypeName] = TypeDescriptor{TypeName: field.Enum.TypeName}
+			}
+		}
+	}
+
+	for _, procedure := range spec.Procedures {
+		walk(procedure.Input)
+		walk(procedure.Output)
+	}
+
+	return declared
+}
+
+// specTypeNames returns the unqualified named types a reflect type string
+// refers to, e.g. "map[string][]*main.Post" gives ["Post"].
+func specTypeNames(typeName string) []string {
+	typeName = strings.TrimLeft(typeName, "*")
+
+	switch {
+	case lo.Contains(specBuiltinTypes, typeName), strings.HasPrefix(typeName, "struct {"), strings.HasPrefix(typeName, "interface {"):
+		return nil
+	case strings.HasPrefix(typeName, "map["):
+		depth := 0
+		for i := len("map"); i < len(typeName); i++ {
+			switch typeName[i] {
+			case '[':
+				depth++
+			case ']':
+				depth--
+				if depth == 0 {
+					return append(specTypeNames(typeName[len("map["):i]), specTypeNames(typeName[i+1:])...)
+				}
+			}
+		}
+		return nil
+	case strings.HasPrefix(typeName, "["):
+		return specTypeNames(typeName[strings.Index(typeName, "]")+1:])
+	}
+
+	return []string{typeName[strings.LastIndex(typeName, ".")+1:]}
+}
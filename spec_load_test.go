@@ -0,0 +1,60 @@
+package xrpc
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+type roundTripUserID int64
+
+type roundTripRole string
+
+type roundTripUser struct {
+	ID      roundTripUserID            `json:"id"`
+	Role    roundTripRole              `json:"role"`
+	Roles   []roundTripRole            `json:"roles"`
+	Owners  map[roundTripUserID]string `json:"owners"`
+	Balance json.Number                `json:"balance"`
+	Address struct {
+		City string `json:"city"`
+	} `json:"address"`
+	Manager *roundTripUser `json:"manager"`
+}
+
+func TestLoadSpecRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "xrpc.yaml")
+
+	app := NewXRPC(XRPCConfig{Name: "Round Trip", SpecPath: path})
+	app.Router("user", NewProcedure[roundTripUser, []roundTripUser]("list").Query(func(c Context[roundTripUser, []roundTripUser]) error {
+		return nil
+	}))
+
+	if err := app.GenerateSpec(); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := LoadSpec(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	underlying := map[string]string{}
+	for _, field := range spec.Procedures[0].Input.Fields {
+		underlying[field.Name] = field.Underlying
+	}
+
+	for name, want := range map[string]string{
+		"ID":      "int64",
+		"Role":    "string",
+		"Roles":   "[]string",
+		"Owners":  "map[int64]string",
+		"Balance": "float64",
+		"Address": "interface {}",
+		"Manager": "",
+	} {
+		if underlying[name] != want {
+			t.Errorf("field %s: underlying %q, want %q", name, underlying[name], want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package xrpc
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/struckchure/xrpc"
+
+// tracing starts a server span per procedure call, continuing the caller's
+// trace from the W3C traceparent header.
+func tracing(tracer trace.Tracer, propagator propagation.TextMapPropagator, path string, t XRPCSpecProcedureType) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", t, path),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("xrpc.path", path),
+					attribute.String("xrpc.type", string(t)),
+					attribute.String("http.request.method", req.Method),
+				),
+			)
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
+			defer c.SetRequest(req)
+
+			err := next(c)
+			if err != nil {
+				span.RecordError(err)
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			span.SetAttributes(
+				attribute.Int("http.response.status_code", status),
+				attribute.String("xrpc.outcome", traceOutcome(c, status)),
+				attribute.String("xrpc.request_id", requestID(c)),
+			)
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			return nil
+		}
+	}
+}
+
+func traceOutcome(c echo.Context, status int) string {
+	switch {
+	case c.Get(validationErrorKey) != nil:
+		return "validation_error"
+	case status >= http.StatusInternalServerError:
+		return "server_error"
+	case status >= http.StatusBadRequest:
+		return "client_error"
+	}
+
+	return "ok"
+}
+
+// traceStep runs fn in a child span of the request's span, e.g. validation,
+// a middleware or the handler. The span is current in the request context
+// while fn runs, so calls it makes are traced beneath it.
+func traceStep(tracer trace.Tracer, c echo.Context, name string, fn func() error, attrs ...attribute.KeyValue) error {
+	req := c.Request()
+	ctx, span := tracer.Start(req.Context(), name, trace.WithAttributes(attrs...))
+	defer span.End()
+
+	c.SetRequest(req.WithContext(ctx))
+	defer c.SetRequest(req)
+
+	err := fn()
+	if err != nil {
+		span.RecordError(err)
+		if xrpcErr, ok := err.(*XRPCError); !ok || xrpcErr.Code >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	return err
+}
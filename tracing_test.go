@@ -0,0 +1,71 @@
+package xrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracingInput struct {
+	Name string `json:"name"`
+}
+
+func TestTracingSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	app := NewXRPC(XRPCConfig{Name: "Tracing", AutoGenTRPCSpec: false, TracerProvider: provider})
+	app.Router("greeting", NewProcedure[tracingInput, string]("hello").Query(func(c Context[tracingInput, string]) error {
+		return c.Json(http.StatusOK, "hello")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/greeting/hello/?name=x", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	app.Server().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	var span *tracetest.SpanStub
+	for _, stub := range exporter.GetSpans() {
+		if stub.SpanKind == trace.SpanKindServer {
+			span = &stub
+		}
+	}
+	if span == nil {
+		t.Fatalf("no server span in %d spans", len(exporter.GetSpans()))
+	}
+
+	if span.Name != "Query /greeting/hello/" {
+		t.Errorf("span name %q", span.Name)
+	}
+
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	for key, want := range map[attribute.Key]attribute.Value{
+		"xrpc.path":                 attribute.StringValue("/greeting/hello/"),
+		"xrpc.type":                 attribute.StringValue("Query"),
+		"http.request.method":       attribute.StringValue(http.MethodGet),
+		"http.response.status_code": attribute.IntValue(http.StatusOK),
+	} {
+		if attributes[key] != want {
+			t.Errorf("attribute %s: %v, want %v", key, attributes[key].Emit(), want.Emit())
+		}
+	}
+
+	if span.Parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("parent %s/%s", span.Parent.TraceID(), span.Parent.SpanID())
+	}
+	if !span.Parent.IsRemote() || span.SpanContext.TraceID() != span.Parent.TraceID() {
+		t.Errorf("span %s does not continue the remote trace", span.SpanContext.TraceID())
+	}
+}
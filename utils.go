@@ -1,26 +1,56 @@
 package xrpc
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
 	"os"
 	"reflect"
 	"strings"
+
+	"github.com/samber/lo"
 )
 
 type TypeDescriptor struct {
-	TypeName string            `yaml:"type_name,omitempty"`
-	Fields   []FieldDescriptor `yaml:"fields,omitempty"`
-	Nillable bool              `yaml:"nillable"`
-	Array    *TypeDescriptor   `yaml:"array,omitempty"`
+	TypeName string            `json:"type_name,omitempty" yaml:"type_name,omitempty"`
+	Fields   []FieldDescriptor `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Nillable bool              `json:"nillable" yaml:"nillable"`
+	Array    *TypeDescriptor   `json:"array,omitempty" yaml:"array,omitempty"`
+	// Type spells types that are neither arrays nor structs clients declare,
+	// like FieldDescriptor.Underlying, e.g. "map[string]int", or "int64" for
+	// a named scalar. It is empty when TypeName suffices.
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
 }
 
 type FieldDescriptor struct {
-	Name     string `yaml:"name"`
-	Alias    string `yaml:"alias"`
-	Type     string `yaml:"type"`
-	Nillable bool   `yaml:"nillable"`
+	Name     string `json:"name" yaml:"name"`
+	Alias    string `json:"alias" yaml:"alias"`
+	Type     string `json:"type" yaml:"type"`
+	Nillable bool   `json:"nillable" yaml:"nillable"`
+	// OmitEmpty is set by the omitempty and omitzero json options, when the
+	// field may be left out of the encoded object.
+	OmitEmpty bool `json:"omitempty,omitempty" yaml:"omitempty,omitempty"`
+	// Underlying spells Type without the named types clients have no
+	// declaration for, e.g. "[]int64" for a []main.UserID field, or "interface
+	// {}" for an anonymous struct. It is empty when Type has none.
+	Underlying string `json:"underlying,omitempty" yaml:"underlying,omitempty"`
+	// Struct describes the named struct a field holds directly, through
+	// pointers, or as slice, array or map elements, so generators can emit
+	// nested types.
+	Struct *TypeDescriptor `json:"struct,omitempty" yaml:"struct,omitempty"`
+	// Description, Example and Format come from the doc, example and format
+	// struct tags, e.g. format:"uuid".
+	Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Example     string          `json:"example,omitempty" yaml:"example,omitempty"`
+	Format      string          `json:"format,omitempty" yaml:"format,omitempty"`
+	Enum        *EnumDescriptor `json:"enum,omitempty" yaml:"enum,omitempty"`
 }
 
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
 func createTypeDescriptor[T any]() TypeDescriptor {
 	var t T
 	typeOfT := reflect.TypeOf(t)
@@ -33,7 +63,12 @@ func createTypeDescriptor[T any]() TypeDescriptor {
 		}
 	}
 
-	// Dereference pointer if it is a pointer
+	return createTypeDescriptorHelper(typeOfT, map[reflect.Type]bool{})
+}
+
+// Helper function to make the recursive call work. seen holds the structs
+// currently being described so self-referencing types terminate.
+func createTypeDescriptorHelper(typeOfT reflect.Type, seen map[reflect.Type]bool) TypeDescriptor {
 	isNillable := typeOfT.Kind() == reflect.Ptr || typeOfT.Kind() == reflect.Interface
 
 	if typeOfT.Kind() == reflect.Ptr {
@@ -46,8 +81,18 @@ func createTypeDescriptor[T any]() TypeDescriptor {
 	}
 
 	if typeOfT.Kind() == reflect.Struct {
-		for i := 0; i < typeOfT.NumField(); i++ {
+		if seen[typeOfT] {
+			return descriptor
+		}
+		seen[typeOfT] = true
+		defer delete(seen, typeOfT)
+
+		for i := range typeOfT.NumField() {
 			field := typeOfT.Field(i)
+			if !field.IsExported() || field.Tag.Get("json") == "-" {
+				continue
+			}
+
 			fieldType := field.Type
 
 			isFieldNillable := fieldType.Kind() == reflect.Ptr || fieldType.Kind() == reflect.Interface
@@ -56,66 +101,123 @@ func createTypeDescriptor[T any]() TypeDescriptor {
 				fieldType = fieldType.Elem()
 			}
 
-			descriptor.Fields = append(descriptor.Fields, FieldDescriptor{
+			fieldDescriptor := FieldDescriptor{
 				Name:     field.Name,
 				Type:     fieldType.String(),
 				Alias:    getFieldAlias(field),
 				Nillable: isFieldNillable,
-			})
+
+				OmitEmpty: getFieldOmitEmpty(field),
+
+				Description: field.Tag.Get("doc"),
+				Example:     field.Tag.Get("example"),
+				Format:      field.Tag.Get("format"),
+				Enum:        fieldEnum(typeOfT, field),
+			}
+
+			if underlying := clientType(fieldType); fieldDescriptor.Enum == nil && underlying != fieldType.String() {
+				fieldDescriptor.Underlying = underlying
+			}
+
+			if structType := nestedStructType(fieldType); structType != nil {
+				nested := createTypeDescriptorHelper(structType, seen)
+				fieldDescriptor.Struct = &nested
+			}
+
+			descriptor.Fields = append(descriptor.Fields, fieldDescriptor)
 		}
 	} else if typeOfT.Kind() == reflect.Slice || typeOfT.Kind() == reflect.Array {
 		elementType := typeOfT.Elem()
 
 		// Recursively call createTypeDescriptor for the element type
-		elementDescriptor := createTypeDescriptorHelper(elementType)
+		elementDescriptor := createTypeDescriptorHelper(elementType, seen)
 
 		descriptor.Array = &elementDescriptor
+		return descriptor
+	}
+
+	spelled := clientType(typeOfT)
+	declared := typeOfT.Kind() == reflect.Struct && spelled == typeOfT.String() && !lo.Contains(specBuiltinTypes, spelled)
+	if !declared && spelled != descriptor.TypeName {
+		descriptor.Type = spelled
 	}
 
 	return descriptor
 }
 
-// Helper function to make the recursive call work.
-func createTypeDescriptorHelper(typeOfT reflect.Type) TypeDescriptor {
-	isNillable := typeOfT.Kind() == reflect.Ptr || typeOfT.Kind() == reflect.Interface
+// nestedStructType unwraps pointers, slices, arrays and map values down to a
+// named struct. Structs that marshal themselves, like time.Time, are skipped.
+func nestedStructType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		case reflect.Struct:
+			if t.Name() == "" ||
+				t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) ||
+				t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+				return nil
+			}
+			return t
+		}
 
-	if typeOfT.Kind() == reflect.Ptr {
-		typeOfT = typeOfT.Elem() // Get the type the pointer points to.
+		return nil
 	}
+}
 
-	descriptor := TypeDescriptor{
-		TypeName: typeOfT.Name(),
-		Nillable: isNillable,
+// clientType spells t with named structs and builtin types kept, and other
+// named types replaced by their underlying type: a number for json.Number,
+// a string for types that marshal as text, and any for other self-marshaling
+// types and anonymous structs.
+func clientType(t reflect.Type) string {
+	switch {
+	case lo.Contains(specBuiltinTypes, t.String()):
+		return t.String()
+	case t == reflect.TypeFor[json.Number]():
+		return "float64"
 	}
 
-	if typeOfT.Kind() == reflect.Struct {
-		for i := range typeOfT.NumField() {
-			field := typeOfT.Field(i)
-			fieldType := field.Type
-
-			isFieldNillable := fieldType.Kind() == reflect.Ptr || fieldType.Kind() == reflect.Interface
+	if t.Name() != "" && t.PkgPath() != "" {
+		switch {
+		case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+			return "string"
+		case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+			return "interface {}"
+		}
+	}
 
-			if fieldType.Kind() == reflect.Ptr {
-				fieldType = fieldType.Elem()
-			}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + clientType(t.Elem())
+	case reflect.Slice:
+		return "[]" + clientType(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), clientType(t.Elem()))
+	case reflect.Map:
+		return "map[" + clientType(t.Key()) + "]" + clientType(t.Elem())
+	case reflect.Struct:
+		return lo.Ternary(t.Name() == "", "interface {}", t.String())
+	case reflect.Interface:
+		return "interface {}"
+	}
 
-			descriptor.Fields = append(descriptor.Fields, FieldDescriptor{
-				Name:     field.Name,
-				Type:     fieldType.String(),
-				Alias:    getFieldAlias(field),
-				Nillable: isFieldNillable,
-			})
-		}
-	} else if typeOfT.Kind() == reflect.Slice || typeOfT.Kind() == reflect.Array {
-		elementType := typeOfT.Elem()
+	return t.Kind().String()
+}
 
-		// Recursively call createTypeDescriptor for the element type
-		elementDescriptor := createTypeDescriptorHelper(elementType)
+// jsonValue converts v to the generic value its JSON encoding decodes to.
+func jsonValue(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
 
-		descriptor.Array = &elementDescriptor
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
 	}
 
-	return descriptor
+	return value
 }
 
 func getFieldAlias(field reflect.StructField) string {
@@ -127,6 +229,11 @@ func getFieldAlias(field reflect.StructField) string {
 	return parts[0]
 }
 
+func getFieldOmitEmpty(field reflect.StructField) bool {
+	options := strings.Split(field.Tag.Get("json"), ",")[1:]
+	return lo.Contains(options, "omitempty") || lo.Contains(options, "omitzero")
+}
+
 // WriteFile creates a new file and writes content to it.
 // It returns an error if the file cannot be created or written to.
 func WriteFile(filename string, content string) error {